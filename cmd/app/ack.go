@@ -0,0 +1,58 @@
+package main
+
+import (
+	"GoHealthChecker/internal/api"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const ackUsage = `Usage:
+  app ack [--api address] [--by name] [--comment text] <url>
+  app ack list [--api address]`
+
+// runAck acknowledges outages of a running checker through its control API,
+// stopping their reminders and escalation.
+func runAck(args []string) int {
+	list := len(args) > 0 && args[0] == "list"
+	if list {
+		args = args[1:]
+	}
+	flags := flag.NewFlagSet("ack", flag.ContinueOnError)
+	address := flags.String("api", "127.0.0.1:8080", "address of the control API")
+	by := flags.String("by", os.Getenv("USER"), "who acknowledges the outage")
+	comment := flags.String("comment", "", "eg. what is being done about the outage")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	client := api.NewClient(*address)
+
+	if list {
+		outages, err := client.Outages()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		for _, outage := range outages {
+			status := "unacknowledged"
+			if outage.Acknowledged {
+				status = fmt.Sprintf("acknowledged by %s: %s", outage.AcknowledgedBy, outage.Comment)
+			}
+			fmt.Printf("%s\tDOWN since %s\tpolicy=%s reminders=%d escalated=%t\t%s\n", outage.Target,
+				outage.Since.Local().Format(time.RFC3339), outage.Policy, outage.Reminders, outage.Escalated, status)
+		}
+		return 0
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, ackUsage)
+		return 2
+	}
+	outage, err := client.Acknowledge(api.AckRequest{Target: flags.Arg(0), By: *by, Comment: *comment})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+	fmt.Printf("Outage of %s acknowledged, no more reminders\n", outage.Target)
+	return 0
+}
@@ -2,17 +2,36 @@ package main
 
 import (
 	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/api"
+	"GoHealthChecker/internal/config"
 	"GoHealthChecker/internal/controller"
 	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/internal/rules"
 	"GoHealthChecker/internal/service"
 	"GoHealthChecker/internal/store"
 	"GoHealthChecker/internal/view"
 	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 )
 
+// stringList is a flag which can be passed multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func signalHandler() (context.Context, context.CancelFunc) {
 	// Create a cancellable context
 	ctx, cancel := context.WithCancel(context.Background())
@@ -31,6 +50,29 @@ func signalHandler() (context.Context, context.CancelFunc) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "silence" {
+		os.Exit(runSilence(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ack" {
+		os.Exit(runAck(os.Args[2:]))
+	}
+
+	storeSpec := flag.String("store", "memory", "where to keep results: memory or file:<directory>")
+	historySize := flag.Int("history-size", store.DefaultHistorySize, "number of results kept in memory per URL")
+	historyAge := flag.Duration("history-age", 0, "drop in-memory results older than this, eg. 1h (0 keeps all)")
+	rawRetention := flag.Duration("retention-raw", 0, "roll up raw results older than this, eg. 168h (0 keeps raw results forever)")
+	minuteRetention := flag.Duration("retention-minute", 0, "delete per-minute rollups older than this (0 keeps them forever)")
+	hourRetention := flag.Duration("retention-hour", 0, "delete per-hour rollups older than this (0 keeps them forever)")
+	configPath := flag.String("config", "", "path to a JSON config file with targets and their settings")
+	fall := flag.Int("fall", 1, "consecutive failures before a target becomes DOWN")
+	rise := flag.Int("rise", 1, "consecutive successes before a target becomes UP again")
+	flapWindow := flag.Duration("flap-window", 10*time.Minute, "window in which state changes are counted for flap detection")
+	flapThreshold := flag.Int("flap-threshold", 5, "state changes within the flap window above which a target is FLAPPING (0 disables)")
+	apiAddress := flag.String("api", "", "address of the control API, eg. 127.0.0.1:8080 (empty disables it)")
+	var webhooks stringList
+	flag.Var(&webhooks, "webhook", "URL to POST state changes of the targets to (can be repeated)")
+	flag.Parse()
+
 	// Initiaize the context and signal handler for CTRL+C handling
 	ctx, _ := signalHandler()
 
@@ -41,18 +83,122 @@ func main() {
 		Context:         ctx,
 		OutputStream:    os.Stdout,
 		MaxQueueSize:    5,
+		Fall:            *fall,
+		Rise:            *rise,
+		FlapWindow:      *flapWindow,
+		FlapThreshold:   *flapThreshold,
+		Targets:         make(map[string]model.TargetSettings),
+	}
+
+	appConfig := &config.Config{}
+	if *configPath != "" {
+		loaded, err := config.Load(*configPath)
+		if err != nil {
+			exitWithError("Error loading the config:", err)
+		}
+		appConfig = loaded
+	}
+	appConfig.Apply(&settings)
+	// Explicitly passed flags take precedence over the config file
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "fall":
+			settings.Fall = *fall
+		case "rise":
+			settings.Rise = *rise
+		case "flap-window":
+			settings.FlapWindow = *flapWindow
+		case "flap-threshold":
+			settings.FlapThreshold = *flapThreshold
+		}
+	})
+	urls := append(flag.Args(), appConfig.URLs()...)
+	for _, webhook := range webhooks {
+		appConfig.Notifiers = append(appConfig.Notifiers, config.Notifier{Type: "webhook", URL: webhook})
+	}
+	notifiers, err := appConfig.NewNotifiers()
+	if err != nil {
+		exitWithError("Error creating the notifiers:", err)
+	}
+
+	alertRules, err := appConfig.NewRules()
+	if err != nil {
+		exitWithError("Error creating the alert rules:", err)
+	}
+	maintenanceManager, err := appConfig.NewMaintenance()
+	if err != nil {
+		exitWithError("Error creating the maintenance windows:", err)
+	}
+	escalations, err := appConfig.NewEscalations()
+	if err != nil {
+		exitWithError("Error creating the escalation policies:", err)
 	}
 
-	inMemoryStore := store.NewInMemoryStore()
+	storeOptions := store.DefaultOptions()
+	storeOptions.History = store.HistoryLimit{MaxResults: *historySize, MaxAge: *historyAge}
+	storeOptions.Retention = store.RetentionPolicy{
+		RawRetention:       *rawRetention,
+		MinuteRetention:    *minuteRetention,
+		HourRetention:      *hourRetention,
+		CompactionInterval: store.DefaultCompactionInterval,
+	}
+	resultStore, err := store.NewStore(*storeSpec, storeOptions)
+	if err != nil {
+		exitWithError("Error opening the store:", err)
+	}
 	CLIView := view.NewCLIView(settings)
-	HTTPService := service.NewHTTPService(settings)
-	appController := controller.NewController(inMemoryStore, CLIView, HTTPService, settings)
+	checker := service.NewDefaultRegistry(settings)
+	appController := controller.NewController(resultStore, CLIView, checker, settings).WithMaintenance(maintenanceManager)
+	// Targets under maintenance are expected to fail
+	mute := func(event notifier.Event) (bool, string) {
+		return maintenanceManager.InMaintenance(event.Target, event.Timestamp)
+	}
+	var dispatcher *notifier.Dispatcher
+	if len(notifiers) > 0 {
+		dispatcher = notifier.NewDispatcher(notifiers, appConfig.NotifierOptions()).WithMute(mute)
+		if grouper := appConfig.NewGrouper(); grouper != nil {
+			dispatcher.WithGrouping(grouper)
+		}
+		appController.WithDispatcher(dispatcher)
+	}
+	escalations.WithDispatcher(dispatcher).WithMute(mute)
+	if len(appConfig.Escalations) > 0 {
+		appController.WithEscalations(escalations)
+	}
+	var apiServer *api.Server
+	if *apiAddress != "" {
+		apiServer = api.NewServer(maintenanceManager).WithEscalations(escalations)
+		if _, err := apiServer.Start(*apiAddress); err != nil {
+			exitWithError("Error starting the control API:", err)
+		}
+	}
+	if len(alertRules) > 0 {
+		engine := rules.NewEngine(resultStore, alertRules, time.Duration(appConfig.RuleInterval), settings.PollingInterval)
+		appController.WithRuleEngine(engine.WithDispatcher(dispatcher))
+	}
 	// Handle failure of the app controller - eg invalid inputs etc.
 	internal.LOGGER.Info("Starting the app.")
-	err := appController.Start(os.Args[1:])
+	err = appController.Start(urls)
+	if apiServer != nil {
+		_ = apiServer.Close(5 * time.Second)
+	}
+	if dispatcher != nil {
+		// Deliver the notifications still in the queue
+		dispatcher.Close()
+	}
+	escalations.Close()
+	if closeErr := resultStore.Close(); closeErr != nil {
+		internal.LOGGER.Error("Error closing the store:" + closeErr.Error())
+	}
 	if err != nil {
 		internal.LOGGER.Error("Error starting the app:" + err.Error())
 		os.Exit(1)
 	}
 	os.Exit(0)
 }
+
+func exitWithError(message string, err error) {
+	internal.LOGGER.Error(message + err.Error())
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
+}
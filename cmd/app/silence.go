@@ -0,0 +1,74 @@
+package main
+
+import (
+	"GoHealthChecker/internal/api"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const silenceUsage = `Usage:
+  app silence add [--api address] [--target url]... [--tag tag]... --duration 1h [--comment text]
+  app silence list [--api address]
+  app silence remove [--api address] <id>`
+
+// runSilence manages silences of a running checker through its control API.
+func runSilence(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, silenceUsage)
+		return 2
+	}
+	command := args[0]
+	flags := flag.NewFlagSet("silence "+command, flag.ContinueOnError)
+	address := flags.String("api", "127.0.0.1:8080", "address of the control API")
+	var targets, tags stringList
+	flags.Var(&targets, "target", "URL to silence (can be repeated)")
+	flags.Var(&tags, "tag", "silence all targets with this tag (can be repeated)")
+	duration := flags.Duration("duration", time.Hour, "how long the silence lasts")
+	comment := flags.String("comment", "", "why the targets are silenced, eg. deploy")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	client := api.NewClient(*address)
+
+	switch command {
+	case "add":
+		silence, err := client.AddSilence(api.SilenceRequest{
+			Targets:  targets,
+			Tags:     tags,
+			Duration: duration.String(),
+			Comment:  *comment,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		fmt.Printf("Silence %s active until %s\n", silence.ID, silence.End.Local().Format(time.RFC3339))
+	case "list":
+		silences, err := client.Silences()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		for _, silence := range silences {
+			fmt.Printf("%s\t%s - %s\ttargets=%v tags=%v\t%s\n", silence.ID,
+				silence.Start.Local().Format(time.RFC3339), silence.End.Local().Format(time.RFC3339),
+				silence.Targets, silence.Tags, silence.Comment)
+		}
+	case "remove":
+		if flags.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, silenceUsage)
+			return 2
+		}
+		if err := client.RemoveSilence(flags.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		fmt.Printf("Silence %s removed\n", flags.Arg(0))
+	default:
+		fmt.Fprintln(os.Stderr, silenceUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"GoHealthChecker/internal/escalation"
+	"GoHealthChecker/internal/maintenance"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client calls the control API of a running checker.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient creates the client, address is eg. "http://127.0.0.1:8080" or just "127.0.0.1:8080".
+func NewClient(address string) *Client {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	return &Client{
+		baseURL: strings.TrimRight(address, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *Client) AddSilence(request SilenceRequest) (maintenance.Silence, error) {
+	var silence maintenance.Silence
+	err := c.do(http.MethodPost, "/silences", request, http.StatusCreated, &silence)
+	return silence, err
+}
+
+func (c *Client) Silences() ([]maintenance.Silence, error) {
+	silences := make([]maintenance.Silence, 0)
+	err := c.do(http.MethodGet, "/silences", nil, http.StatusOK, &silences)
+	return silences, err
+}
+
+func (c *Client) RemoveSilence(id string) error {
+	return c.do(http.MethodDelete, "/silences/"+url.PathEscape(id), nil, http.StatusNoContent, nil)
+}
+
+func (c *Client) Acknowledge(request AckRequest) (escalation.Outage, error) {
+	var outage escalation.Outage
+	err := c.do(http.MethodPost, "/acks", request, http.StatusOK, &outage)
+	return outage, err
+}
+
+func (c *Client) Outages() ([]escalation.Outage, error) {
+	outages := make([]escalation.Outage, 0)
+	err := c.do(http.MethodGet, "/outages", nil, http.StatusOK, &outages)
+	return outages, err
+}
+
+// do sends the request with body encoded as JSON and decodes the response into result.
+func (c *Client) do(method string, path string, body any, expectedStatus int, result any) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != expectedStatus {
+		var failure errorResponse
+		if json.NewDecoder(response.Body).Decode(&failure) == nil && failure.Error != "" {
+			return fmt.Errorf("%s %s: %s", method, path, failure.Error)
+		}
+		return fmt.Errorf("%s %s: unexpected status %d", method, path, response.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
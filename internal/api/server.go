@@ -0,0 +1,168 @@
+// Package api
+//
+// Server is the HTTP control API of a running checker, eg. to silence targets
+// during a deploy or acknowledge outages. Client talks to it from the command line.
+
+package api
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/escalation"
+	"GoHealthChecker/internal/maintenance"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// SilenceRequest creates a silence lasting Duration (eg. "1h") from now,
+// or the absolute range [Start, End).
+type SilenceRequest struct {
+	Targets  []string  `json:"targets,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Start    time.Time `json:"start,omitzero"`
+	End      time.Time `json:"end,omitzero"`
+	Duration string    `json:"duration,omitempty"`
+	Comment  string    `json:"comment,omitempty"`
+}
+
+// AckRequest acknowledges the outage of Target, stopping its reminders and escalation.
+type AckRequest struct {
+	Target  string `json:"target"`
+	By      string `json:"by,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type Server struct {
+	maintenance *maintenance.Manager
+	escalations *escalation.Manager
+	mux         *http.ServeMux
+	server      *http.Server
+}
+
+func NewServer(manager *maintenance.Manager) *Server {
+	s := &Server{maintenance: manager, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /silences", s.listSilences)
+	s.mux.HandleFunc("POST /silences", s.addSilence)
+	s.mux.HandleFunc("DELETE /silences/{id}", s.removeSilence)
+	s.server = &http.Server{Handler: s.mux, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// WithEscalations serves the outages followed by manager and their acknowledgements.
+func (s *Server) WithEscalations(manager *escalation.Manager) *Server {
+	s.escalations = manager
+	s.mux.HandleFunc("GET /outages", s.listOutages)
+	s.mux.HandleFunc("POST /acks", s.acknowledge)
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// Start listens on address (eg. "127.0.0.1:8080") and serves the API in the background.
+// It returns the address actually listened on, useful with port 0.
+func (s *Server) Start(address string) (string, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			internal.LOGGER.Error(fmt.Sprintf("Control API stopped: %s", err))
+		}
+	}()
+	internal.LOGGER.Info(fmt.Sprintf("Control API listening on %s", listener.Addr()))
+	return listener.Addr().String(), nil
+}
+
+// Close stops the server, waiting for running requests at most timeout.
+func (s *Server) Close(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+func (s *Server) listSilences(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.maintenance.Silences(time.Now()))
+}
+
+func (s *Server) addSilence(w http.ResponseWriter, r *http.Request) {
+	var request SilenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	silence := maintenance.Silence{
+		Targets: request.Targets,
+		Tags:    request.Tags,
+		Start:   request.Start,
+		End:     request.End,
+		Comment: request.Comment,
+	}
+	if request.Duration != "" {
+		duration, err := time.ParseDuration(request.Duration)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid duration: " + err.Error()})
+			return
+		}
+		if silence.Start.IsZero() {
+			silence.Start = time.Now().UTC()
+		}
+		silence.End = silence.Start.Add(duration)
+	}
+
+	created, err := s.maintenance.AddSilence(silence)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	internal.LOGGER.Info(fmt.Sprintf("Silence %s created until %s: %s", created.ID, created.End.Format(time.RFC3339), created.Comment))
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) removeSilence(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !s.maintenance.RemoveSilence(id) {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "no silence " + id})
+		return
+	}
+	internal.LOGGER.Info(fmt.Sprintf("Silence %s removed", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listOutages(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.escalations.Outages())
+}
+
+func (s *Server) acknowledge(w http.ResponseWriter, r *http.Request) {
+	var request AckRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	if request.Target == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "target is required"})
+		return
+	}
+	outage, err := s.escalations.Acknowledge(request.Target, request.By, request.Comment)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, outage)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,25 @@
+package internal
+
+import "bytes"
+
+// LimitedBuffer keeps the first limit bytes written and discards the rest,
+// eg. to capture the output of a command which could print without end.
+type LimitedBuffer struct {
+	buffer bytes.Buffer
+	limit  int
+}
+
+func NewLimitedBuffer(limit int) *LimitedBuffer {
+	return &LimitedBuffer{limit: limit}
+}
+
+func (b *LimitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buffer.Len(); remaining > 0 {
+		b.buffer.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
+func (b *LimitedBuffer) String() string {
+	return b.buffer.String()
+}
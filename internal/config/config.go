@@ -0,0 +1,459 @@
+// Package config
+//
+// Config is the optional JSON configuration file passed with --config.
+// It holds settings which don't fit on the command line, eg. per-target thresholds.
+
+package config
+
+import (
+	"GoHealthChecker/internal/escalation"
+	"GoHealthChecker/internal/maintenance"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/internal/rules"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+type Config struct {
+	Fall          int          `json:"fall,omitempty"`
+	Rise          int          `json:"rise,omitempty"`
+	FlapWindow    Duration     `json:"flap_window,omitempty"`
+	FlapThreshold int          `json:"flap_threshold,omitempty"` // State changes within flap_window above which a target is FLAPPING
+	Targets       []Target     `json:"targets,omitempty"`
+	Notifiers     []Notifier   `json:"notifiers,omitempty"`
+	Notification  Notification `json:"notification"`
+	Rules         []Rule       `json:"rules,omitempty"`
+	RuleInterval  Duration     `json:"rule_interval,omitempty"` // How often the rules are evaluated
+	Maintenance   []Window     `json:"maintenance,omitempty"`
+	Escalations   []Escalation `json:"escalations,omitempty"`
+	Grouping      Grouping     `json:"grouping"`
+}
+
+type Target struct {
+	URL    string            `json:"url"`
+	Fall   int               `json:"fall,omitempty"`
+	Rise   int               `json:"rise,omitempty"`
+	Tags   []string          `json:"tags,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"` // Eg. {"group": "payments"}, used for grouping notifications
+}
+
+// Grouping aggregates state changes arriving within window into one notification,
+// for targets sharing the value of a label from by, or failing with the same class of error.
+type Grouping struct {
+	Window     Duration `json:"window,omitempty"`
+	By         []string `json:"by,omitempty"`
+	ErrorClass bool     `json:"error_class,omitempty"`
+}
+
+// Window is a scheduled maintenance window, either absolute (start, end) or
+// recurring (a cron-like schedule, eg. "0 2 * * 0", and a duration).
+type Window struct {
+	Name     string    `json:"name"`
+	Targets  []string  `json:"targets,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Start    time.Time `json:"start,omitzero"`
+	End      time.Time `json:"end,omitzero"`
+	Schedule string    `json:"schedule,omitempty"`
+	Duration Duration  `json:"duration,omitempty"`
+}
+
+// Notifier is a notification channel, Type selects the implementation.
+type Notifier struct {
+	Type    string            `json:"type"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Format  string            `json:"format,omitempty"` // Message format of webhooks: json, slack or teams
+
+	// Email settings
+	Host               string   `json:"host,omitempty"`
+	Port               int      `json:"port,omitempty"`
+	Username           string   `json:"username,omitempty"`
+	Password           string   `json:"password,omitempty"`
+	PasswordEnv        string   `json:"password_env,omitempty"` // Read the password from this environment variable
+	From               string   `json:"from,omitempty"`
+	To                 []string `json:"to,omitempty"`
+	TLS                string   `json:"tls,omitempty"` // starttls (default), implicit or none
+	InsecureSkipVerify bool     `json:"insecure_skip_verify,omitempty"`
+	BatchWindow        Duration `json:"batch_window,omitempty"`
+
+	// PagerDuty and Opsgenie settings, URL overrides the default endpoint
+	RoutingKey    string `json:"routing_key,omitempty"`
+	RoutingKeyEnv string `json:"routing_key_env,omitempty"`
+	APIKey        string `json:"api_key,omitempty"`
+	APIKeyEnv     string `json:"api_key_env,omitempty"`
+	Severity      string `json:"severity,omitempty"` // PagerDuty severity: critical, error, warning or info
+	Priority      string `json:"priority,omitempty"` // Opsgenie priority: P1 - P5
+
+	// Exec settings, the command is run directly, not through a shell
+	Command   []string `json:"command,omitempty"`
+	Timeout   Duration `json:"timeout,omitempty"`
+	Retries   int      `json:"retries,omitempty"`    // Additional runs of a failed command, none by default
+	AllEvents bool     `json:"all_events,omitempty"` // Run also for flapping, alerts, escalations and groups
+}
+
+// Rule is an alert rule, eg. {"name": "slow", "expr": "p95(latency, 5m) > 800ms", "for": "2m"}.
+type Rule struct {
+	Name     string            `json:"name"`
+	Expr     string            `json:"expr"`
+	Severity string            `json:"severity,omitempty"` // critical, error, warning (default) or info
+	For      Duration          `json:"for,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Targets  []string          `json:"targets,omitempty"` // URLs the rule applies to, all when empty
+}
+
+// Escalation is a policy for targets staying DOWN: re-notify every repeat_interval
+// and notify the escalate_to channels after escalate_after, until acknowledged.
+type Escalation struct {
+	Name           string     `json:"name"`
+	Targets        []string   `json:"targets,omitempty"`
+	Tags           []string   `json:"tags,omitempty"`
+	RepeatInterval Duration   `json:"repeat_interval,omitempty"`
+	EscalateAfter  Duration   `json:"escalate_after,omitempty"`
+	EscalateTo     []Notifier `json:"escalate_to,omitempty"`
+}
+
+// Notification configures the delivery of notifications, zero values keep the defaults.
+type Notification struct {
+	Retries      *int     `json:"retries,omitempty"`
+	RetryBackoff Duration `json:"retry_backoff,omitempty"`
+	Timeout      Duration `json:"timeout,omitempty"`
+}
+
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return &config, nil
+}
+
+// URLs returns the URLs of all configured targets.
+func (c *Config) URLs() []string {
+	urls := make([]string, 0, len(c.Targets))
+	for _, target := range c.Targets {
+		urls = append(urls, target.URL)
+	}
+	return urls
+}
+
+// Apply copies the configuration into the application settings.
+func (c *Config) Apply(settings *model.AppSettings) {
+	if c.Fall > 0 {
+		settings.Fall = c.Fall
+	}
+	if c.Rise > 0 {
+		settings.Rise = c.Rise
+	}
+	if c.FlapWindow > 0 {
+		settings.FlapWindow = time.Duration(c.FlapWindow)
+	}
+	if c.FlapThreshold > 0 {
+		settings.FlapThreshold = c.FlapThreshold
+	}
+	for _, target := range c.Targets {
+		settings.WithTargetSettings(target.URL, model.TargetSettings{
+			Fall: target.Fall,
+			Rise: target.Rise,
+		})
+	}
+}
+
+// NewNotifiers creates the configured notification channels.
+func (c *Config) NewNotifiers() ([]notifier.Notifier, error) {
+	return newNotifiers(c.Notifiers)
+}
+
+func newNotifiers(channels []Notifier) ([]notifier.Notifier, error) {
+	notifiers := make([]notifier.Notifier, 0, len(channels))
+	for _, channel := range channels {
+		switch channel.Type {
+		case "webhook", "slack", "teams":
+			format := channel.Format
+			if format == "" && channel.Type != "webhook" {
+				format = channel.Type
+			}
+			formatter, err := notifier.NewFormatter(format)
+			if err != nil {
+				return nil, err
+			}
+			notifiers = append(notifiers, notifier.NewWebhookNotifier(channel.URL, channel.Headers).WithFormatter(formatter))
+		case "email":
+			password := secret(channel.Password, channel.PasswordEnv)
+			notifiers = append(notifiers, notifier.NewSMTPNotifier(notifier.SMTPSettings{
+				Host:               channel.Host,
+				Port:               channel.Port,
+				Username:           channel.Username,
+				Password:           password,
+				From:               channel.From,
+				To:                 channel.To,
+				TLS:                channel.TLS,
+				InsecureSkipVerify: channel.InsecureSkipVerify,
+				BatchWindow:        time.Duration(channel.BatchWindow),
+			}))
+		case "pagerduty":
+			notifiers = append(notifiers, notifier.NewPagerDutyNotifier(
+				channel.URL, secret(channel.RoutingKey, channel.RoutingKeyEnv), channel.Severity,
+			))
+		case "opsgenie":
+			notifiers = append(notifiers, notifier.NewOpsgenieNotifier(
+				channel.URL, secret(channel.APIKey, channel.APIKeyEnv), channel.Priority,
+			))
+		case "exec":
+			execNotifier := notifier.NewExecNotifier(channel.Command).
+				WithTimeout(time.Duration(channel.Timeout)).
+				WithRetries(channel.Retries)
+			if channel.AllEvents {
+				execNotifier.WithAllEvents()
+			}
+			notifiers = append(notifiers, execNotifier)
+		default:
+			return nil, fmt.Errorf("unsupported notifier type: %s", channel.Type)
+		}
+	}
+	return notifiers, nil
+}
+
+// secret returns the value of the environment variable env if set, value otherwise.
+func secret(value string, env string) string {
+	if env != "" {
+		return os.Getenv(env)
+	}
+	return value
+}
+
+// NewGrouper creates the grouper of the notifications, nil when grouping is not configured.
+func (c *Config) NewGrouper() *notifier.Grouper {
+	if len(c.Grouping.By) == 0 && !c.Grouping.ErrorClass {
+		return nil
+	}
+	labels := make(map[string]map[string]string)
+	for _, target := range c.Targets {
+		if len(target.Labels) > 0 {
+			labels[target.URL] = target.Labels
+		}
+	}
+	grouper := notifier.NewGrouper(time.Duration(c.Grouping.Window), c.Grouping.By, labels)
+	if c.Grouping.ErrorClass {
+		grouper.WithErrorClass()
+	}
+	return grouper
+}
+
+// NewRules creates the configured alert rules.
+func (c *Config) NewRules() ([]*rules.Rule, error) {
+	alertRules := make([]*rules.Rule, 0, len(c.Rules))
+	for _, rule := range c.Rules {
+		alertRule, err := rules.NewRule(rule.Name, rule.Expr, rule.Severity, time.Duration(rule.For))
+		if err != nil {
+			return nil, err
+		}
+		alertRules = append(alertRules, alertRule.WithLabels(rule.Labels).WithTargets(rule.Targets))
+	}
+	return alertRules, nil
+}
+
+// NewMaintenance creates the maintenance manager with the configured windows and the tags of the targets.
+func (c *Config) NewMaintenance() (*maintenance.Manager, error) {
+	windows := make([]maintenance.Window, 0, len(c.Maintenance))
+	for i, window := range c.Maintenance {
+		name := window.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if len(window.Targets) == 0 && len(window.Tags) == 0 {
+			return nil, fmt.Errorf("maintenance window %s requires targets or tags", name)
+		}
+		converted := maintenance.Window{
+			Name:     name,
+			Targets:  window.Targets,
+			Tags:     window.Tags,
+			Start:    window.Start,
+			End:      window.End,
+			Duration: time.Duration(window.Duration),
+		}
+		switch {
+		case window.Schedule != "":
+			schedule, err := maintenance.ParseSchedule(window.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance window %s: %w", name, err)
+			}
+			if converted.Duration <= 0 {
+				return nil, fmt.Errorf("maintenance window %s: schedule requires a duration", name)
+			}
+			converted.Schedule = schedule
+		case !window.End.After(window.Start):
+			return nil, fmt.Errorf("maintenance window %s requires a schedule, or start and end", name)
+		}
+		windows = append(windows, converted)
+	}
+
+	return maintenance.NewManager(windows, c.Tags()), nil
+}
+
+// Tags returns the tags of the targets by their URL.
+func (c *Config) Tags() map[string][]string {
+	tags := make(map[string][]string)
+	for _, target := range c.Targets {
+		if len(target.Tags) > 0 {
+			tags[target.URL] = target.Tags
+		}
+	}
+	return tags
+}
+
+// NewEscalations creates the escalation manager with the configured policies.
+// Every policy escalating to some channels gets its own Dispatcher, closed by the manager.
+func (c *Config) NewEscalations() (*escalation.Manager, error) {
+	policies := make([]*escalation.Policy, 0, len(c.Escalations))
+	for i, policy := range c.Escalations {
+		converted := &escalation.Policy{
+			Name:           escalationName(i, policy),
+			Targets:        policy.Targets,
+			Tags:           policy.Tags,
+			RepeatInterval: time.Duration(policy.RepeatInterval),
+			EscalateAfter:  time.Duration(policy.EscalateAfter),
+		}
+		if len(policy.EscalateTo) > 0 {
+			notifiers, err := newNotifiers(policy.EscalateTo)
+			if err != nil {
+				return nil, fmt.Errorf("escalation %s: %w", converted.Name, err)
+			}
+			converted.Escalation = notifier.NewDispatcher(notifiers, c.NotifierOptions())
+		}
+		policies = append(policies, converted)
+	}
+	return escalation.NewManager(policies, c.Tags(), escalation.DefaultCheckInterval), nil
+}
+
+func escalationName(i int, policy Escalation) string {
+	if policy.Name != "" {
+		return policy.Name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// NotifierOptions returns the delivery options with the configured values applied.
+func (c *Config) NotifierOptions() notifier.Options {
+	options := notifier.DefaultOptions()
+	if c.Notification.Retries != nil {
+		options.Retries = *c.Notification.Retries
+	}
+	if c.Notification.RetryBackoff > 0 {
+		options.RetryBackoff = time.Duration(c.Notification.RetryBackoff)
+	}
+	if c.Notification.Timeout > 0 {
+		options.Timeout = time.Duration(c.Notification.Timeout)
+	}
+	return options
+}
+
+func (c *Config) validate() error {
+	if c.Fall < 0 || c.Rise < 0 {
+		return fmt.Errorf("fall and rise must not be negative")
+	}
+	if c.FlapWindow < 0 || c.FlapThreshold < 0 {
+		return fmt.Errorf("flap_window and flap_threshold must not be negative")
+	}
+	for i, target := range c.Targets {
+		if target.URL == "" {
+			return fmt.Errorf("target #%d has no url", i+1)
+		}
+		if target.Fall < 0 || target.Rise < 0 {
+			return fmt.Errorf("target %s: fall and rise must not be negative", target.URL)
+		}
+	}
+	for i, channel := range c.Notifiers {
+		if err := validateNotifier(fmt.Sprintf("notifier #%d", i+1), channel); err != nil {
+			return err
+		}
+	}
+	if c.Notification.Retries != nil && *c.Notification.Retries < 0 {
+		return fmt.Errorf("notification retries must not be negative")
+	}
+	names := make(map[string]bool)
+	for i, rule := range c.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule #%d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule %s", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	if _, err := c.NewRules(); err != nil {
+		return err
+	}
+	if _, err := c.NewMaintenance(); err != nil {
+		return err
+	}
+	if c.Grouping.Window < 0 {
+		return fmt.Errorf("grouping window must not be negative")
+	}
+	for i, policy := range c.Escalations {
+		name := "escalation " + escalationName(i, policy)
+		if len(policy.Targets) == 0 && len(policy.Tags) == 0 {
+			return fmt.Errorf("%s requires targets or tags", name)
+		}
+		if policy.RepeatInterval < 0 || policy.EscalateAfter < 0 {
+			return fmt.Errorf("%s: repeat_interval and escalate_after must not be negative", name)
+		}
+		if policy.RepeatInterval == 0 && policy.EscalateAfter == 0 {
+			return fmt.Errorf("%s requires repeat_interval or escalate_after", name)
+		}
+		if (policy.EscalateAfter > 0) != (len(policy.EscalateTo) > 0) {
+			return fmt.Errorf("%s: escalate_after and escalate_to must be set together", name)
+		}
+		for j, channel := range policy.EscalateTo {
+			if err := validateNotifier(fmt.Sprintf("%s notifier #%d", name, j+1), channel); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateNotifier(name string, channel Notifier) error {
+	if channel.Type == "" {
+		return fmt.Errorf("%s has no type", name)
+	}
+	if (channel.Type == "webhook" || channel.Type == "slack" || channel.Type == "teams") && channel.URL == "" {
+		return fmt.Errorf("%s: %s requires url", name, channel.Type)
+	}
+	if channel.Type == "pagerduty" && channel.RoutingKey == "" && channel.RoutingKeyEnv == "" {
+		return fmt.Errorf("%s: pagerduty requires routing_key or routing_key_env", name)
+	}
+	if channel.Type == "opsgenie" && channel.APIKey == "" && channel.APIKeyEnv == "" {
+		return fmt.Errorf("%s: opsgenie requires api_key or api_key_env", name)
+	}
+	if channel.Type == "exec" && len(channel.Command) == 0 {
+		return fmt.Errorf("%s: exec requires command", name)
+	}
+	if channel.Type == "exec" && channel.Retries < 0 {
+		return fmt.Errorf("%s: exec retries must not be negative", name)
+	}
+	if channel.Type == "email" {
+		if channel.Host == "" || channel.Port <= 0 || channel.From == "" || len(channel.To) == 0 {
+			return fmt.Errorf("%s: email requires host, port, from and to", name)
+		}
+		switch channel.TLS {
+		case "", notifier.SMTPTLSStartTLS, notifier.SMTPTLSImplicit, notifier.SMTPTLSNone:
+		default:
+			return fmt.Errorf("%s: unsupported tls mode %s", name, channel.TLS)
+		}
+	}
+	return nil
+}
@@ -7,8 +7,13 @@ package controller
 
 import (
 	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/escalation"
+	"GoHealthChecker/internal/maintenance"
 	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/internal/rules"
 	"GoHealthChecker/internal/service"
+	"GoHealthChecker/internal/state"
 	"GoHealthChecker/internal/store"
 	"GoHealthChecker/internal/view"
 	"context"
@@ -19,15 +24,31 @@ import (
 )
 
 type Controller struct {
+	Checker      service.Service // Checks the URLs, a service.Registry routes them by their scheme
+	Store        store.Store
+	View         view.View
+	StateTracker *state.Tracker
+	FlapDetector *state.FlapDetector
+	Dispatcher   *notifier.Dispatcher // Optional, receives state changes of the targets
+	RuleEngine   *rules.Engine        // Optional, evaluates alert rules while the checks run
+	Maintenance  *maintenance.Manager // Optional, flags results of targets under maintenance
+	Escalations  *escalation.Manager  // Optional, re-notifies and escalates targets staying DOWN
+	// Deprecated: HTTPService is the former name of Checker, it is used only when Checker is nil.
 	HTTPService    service.Service
-	Store          store.Store
-	View           view.View
 	workersWg      sync.WaitGroup
 	workerChannels map[string]chan struct{}
 	channelsMutex  sync.RWMutex
+	muted          map[string]mutedState // State of the targets before their state changed under maintenance
+	mutedMutex     sync.Mutex
 	settings       model.AppSettings
 }
 
+// mutedState is the last state notified of a target whose state changed under maintenance.
+type mutedState struct {
+	state model.State
+	since time.Time
+}
+
 func NewController(
 	store store.Store,
 	view view.View,
@@ -35,16 +56,40 @@ func NewController(
 	settings model.AppSettings,
 ) *Controller {
 	return &Controller{
+		Checker:        service,
 		HTTPService:    service,
 		Store:          store,
 		View:           view,
+		StateTracker:   state.NewTracker(settings),
+		FlapDetector:   state.NewFlapDetector(settings),
 		workersWg:      sync.WaitGroup{},
 		workerChannels: make(map[string]chan struct{}),
 		channelsMutex:  sync.RWMutex{},
+		muted:          make(map[string]mutedState),
 		settings:       settings,
 	}
 }
 
+func (controller *Controller) WithDispatcher(dispatcher *notifier.Dispatcher) *Controller {
+	controller.Dispatcher = dispatcher
+	return controller
+}
+
+func (controller *Controller) WithRuleEngine(engine *rules.Engine) *Controller {
+	controller.RuleEngine = engine
+	return controller
+}
+
+func (controller *Controller) WithMaintenance(manager *maintenance.Manager) *Controller {
+	controller.Maintenance = manager
+	return controller
+}
+
+func (controller *Controller) WithEscalations(manager *escalation.Manager) *Controller {
+	controller.Escalations = manager
+	return controller
+}
+
 func (controller *Controller) Start(urls []string) error {
 	// Parse args and load them to Store
 	err := controller.validateInput(urls)
@@ -60,7 +105,21 @@ func (controller *Controller) Start(urls []string) error {
 	// Add elements to Queues
 	internal.LOGGER.Info("Spawning workers for each URL")
 	// Init workers to process the queues
-	_, cancel := controller.startWorkers()
+	workerCtx, cancel := controller.startWorkers()
+	if controller.RuleEngine != nil {
+		controller.workersWg.Add(1)
+		go func() {
+			defer controller.workersWg.Done()
+			controller.RuleEngine.Run(workerCtx)
+		}()
+	}
+	if controller.Escalations != nil {
+		controller.workersWg.Add(1)
+		go func() {
+			defer controller.workersWg.Done()
+			controller.Escalations.Run(workerCtx)
+		}()
+	}
 	// Initial queue population, next will be done by ticker after N seconds
 	controller.addToQueue()
 	for {
@@ -130,19 +189,135 @@ func (controller *Controller) startWorkers() (context.Context, context.CancelFun
 }
 
 func (controller *Controller) checkURLAndRender(url string) {
-	resp, err := controller.HTTPService.CheckUrl(url)
+	resp, err := controller.checker().CheckUrl(url)
 	if err != nil {
 		internal.LOGGER.Error(fmt.Sprintf("Error when requesting %s: %s", url, err))
 	}
+	if controller.Maintenance != nil {
+		resp.Maintenance, _ = controller.Maintenance.InMaintenance(url, resp.Timestamp)
+	}
+	// Compute the effective state before the result reaches the store and the views
+	resp, transition := controller.StateTracker.Observe(url, resp)
+	resp, flap := controller.FlapDetector.Observe(url, resp, transition)
+	if transition != nil {
+		internal.LOGGER.Info(fmt.Sprintf("%s changed state %s -> %s", url, transition.From, transition.To))
+	}
+	if flap != nil {
+		internal.LOGGER.Info(fmt.Sprintf("%s flapping: %t, %d state changes within %s", url, flap.Flapping, flap.Changes, flap.Window))
+	}
+	transition = controller.unmute(url, resp, transition)
 	controller.Store.SaveResult(url, resp)
+	switch {
+	case flap != nil:
+		controller.notifyFlap(*flap, resp)
+	case transition != nil && !resp.Flapping:
+		// State changes of flapping targets are suppressed
+		controller.notify(*transition)
+	}
 	controller.View.Render(controller.Store.GetLatestResults())
 }
 
+// unmute returns the transition to notify once a window or silence of url is over. The notifications
+// of the state changes under maintenance are muted, so the first result after it notifies the change
+// from the state notified before, eg. the DOWN of a target which failed during the window and is still DOWN.
+func (controller *Controller) unmute(url string, result model.HealthCheckResult, transition *state.Transition) *state.Transition {
+	controller.mutedMutex.Lock()
+	defer controller.mutedMutex.Unlock()
+
+	muted, exists := controller.muted[url]
+	if result.Maintenance {
+		if !exists && transition != nil {
+			muted = mutedState{state: transition.From}
+			if !transition.First {
+				muted.since = transition.Timestamp.Add(-transition.Duration)
+			}
+			controller.muted[url] = muted
+		}
+		return transition
+	}
+	if !exists {
+		return transition
+	}
+	delete(controller.muted, url)
+	if result.State == muted.state {
+		return nil
+	}
+	unmuted := state.Transition{URL: url, To: result.State, Result: result, Timestamp: result.Timestamp}
+	if transition != nil {
+		unmuted = *transition
+	}
+	unmuted.From, unmuted.First = muted.state, muted.since.IsZero()
+	unmuted.Duration = 0
+	if !muted.since.IsZero() {
+		unmuted.Duration = unmuted.Timestamp.Sub(muted.since)
+	}
+	return &unmuted
+}
+
+func (controller *Controller) notify(transition state.Transition) {
+	// Targets coming UP right after the start are not worth a notification
+	if transition.First && transition.To == model.StateUp {
+		return
+	}
+	controller.dispatch(notifier.NewStateChangeEvent(transition))
+}
+
+func (controller *Controller) notifyFlap(flap state.FlapChange, result model.HealthCheckResult) {
+	controller.dispatch(notifier.NewFlapEvent(flap, result))
+	// The state may have changed while the notifications were suppressed
+	if !flap.Flapping && flap.Notified != flap.State {
+		controller.notify(state.Transition{
+			URL:       flap.URL,
+			From:      flap.Notified,
+			To:        flap.State,
+			Result:    result,
+			Timestamp: flap.Timestamp,
+			Duration:  flap.Timestamp.Sub(flap.Since),
+		})
+	}
+}
+
+func (controller *Controller) dispatch(event notifier.Event) {
+	if controller.Dispatcher == nil && controller.Escalations == nil {
+		return
+	}
+	// A bounded range is served from memory, the file store would read all its segments otherwise
+	since := event.Timestamp.Add(-notifier.HistorySize * controller.settings.PollingInterval)
+	history := controller.Store.GetHistory(event.Target, since, time.Time{})
+	event.History = history[max(len(history)-notifier.HistorySize, 0):]
+	if controller.Escalations != nil {
+		controller.Escalations.Observe(event)
+	}
+	if controller.Dispatcher != nil {
+		controller.Dispatcher.Dispatch(event)
+	}
+}
+
+// checker returns the service checking the URLs, HTTPService if only the deprecated field is set.
+func (controller *Controller) checker() service.Service {
+	if controller.Checker == nil {
+		return controller.HTTPService
+	}
+	return controller.Checker
+}
+
 func (controller *Controller) validateInput(urls []string) error {
 	if len(urls) == 0 {
 		return fmt.Errorf("no URLs provided")
 	}
 
+	// Every URL is validated before the first check, by its checker if it implements service.Validator,
+	// other checkers are HTTP checkers and get only http:// and https:// URLs
+	checker := controller.checker()
+	validate := service.NewRegistry(checker).ValidateURL
+	if validator, ok := checker.(service.Validator); ok {
+		validate = validator.ValidateURL
+	}
+	for _, url := range urls {
+		if err := validate(url); err != nil {
+			return fmt.Errorf("invalid URL %s: %w", url, err)
+		}
+	}
 	for _, url := range urls {
 		if err := controller.Store.AddURL(url); err != nil {
 			return fmt.Errorf("failed to add URL %s: %w", url, err)
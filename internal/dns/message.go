@@ -0,0 +1,352 @@
+// Package dns
+//
+// Minimal DNS wire format (RFC 1035) for the dns:// health checks: queries with a single
+// question and the answers of the common record types, with name compression on decoding.
+
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+type Type uint16
+
+const (
+	TypeA     Type = 1
+	TypeNS    Type = 2
+	TypeCNAME Type = 5
+	TypeMX    Type = 15
+	TypeTXT   Type = 16
+	TypeAAAA  Type = 28
+)
+
+const classINET = 1
+
+var typeNames = map[Type]string{
+	TypeA:     "A",
+	TypeNS:    "NS",
+	TypeCNAME: "CNAME",
+	TypeMX:    "MX",
+	TypeTXT:   "TXT",
+	TypeAAAA:  "AAAA",
+}
+
+// ParseType returns the record type of its name, eg. "AAAA".
+func ParseType(name string) (Type, error) {
+	for recordType, typeName := range typeNames {
+		if strings.EqualFold(name, typeName) {
+			return recordType, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported record type: %s", name)
+}
+
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+// RCode is the response code of the server.
+type RCode uint8
+
+const (
+	RCodeSuccess        RCode = 0
+	RCodeFormatError    RCode = 1
+	RCodeServerFailure  RCode = 2
+	RCodeNameError      RCode = 3
+	RCodeNotImplemented RCode = 4
+	RCodeRefused        RCode = 5
+)
+
+func (r RCode) String() string {
+	switch r {
+	case RCodeSuccess:
+		return "NOERROR"
+	case RCodeFormatError:
+		return "FORMERR"
+	case RCodeServerFailure:
+		return "SERVFAIL"
+	case RCodeNameError:
+		return "NXDOMAIN"
+	case RCodeNotImplemented:
+		return "NOTIMP"
+	case RCodeRefused:
+		return "REFUSED"
+	default:
+		return "RCODE" + strconv.Itoa(int(r))
+	}
+}
+
+type Question struct {
+	Name string // Fully qualified, eg. "api.internal."
+	Type Type
+}
+
+// Record is a resource record with its data in text form: the address for A and AAAA,
+// the name for CNAME and NS, "preference exchange" for MX and the joined strings for TXT.
+type Record struct {
+	Name  string
+	Type  Type
+	TTL   uint32
+	Value string
+}
+
+type Message struct {
+	ID                 uint16
+	Response           bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	RCode              RCode
+	Questions          []Question
+	Answers            []Record
+}
+
+// Fqdn returns name with the trailing dot.
+func Fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// Pack encodes the message, names are not compressed.
+func (m Message) Pack() ([]byte, error) {
+	data := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(data[0:], m.ID)
+	var flags uint16
+	if m.Response {
+		flags |= 1 << 15
+	}
+	if m.Truncated {
+		flags |= 1 << 9
+	}
+	if m.RecursionDesired {
+		flags |= 1 << 8
+	}
+	if m.RecursionAvailable {
+		flags |= 1 << 7
+	}
+	flags |= uint16(m.RCode & 0xF)
+	binary.BigEndian.PutUint16(data[2:], flags)
+	binary.BigEndian.PutUint16(data[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(data[6:], uint16(len(m.Answers)))
+
+	var err error
+	for _, question := range m.Questions {
+		if data, err = appendName(data, question.Name); err != nil {
+			return nil, err
+		}
+		data = binary.BigEndian.AppendUint16(data, uint16(question.Type))
+		data = binary.BigEndian.AppendUint16(data, classINET)
+	}
+	for _, record := range m.Answers {
+		if data, err = appendName(data, record.Name); err != nil {
+			return nil, err
+		}
+		data = binary.BigEndian.AppendUint16(data, uint16(record.Type))
+		data = binary.BigEndian.AppendUint16(data, classINET)
+		data = binary.BigEndian.AppendUint32(data, record.TTL)
+		rdata, err := packData(record)
+		if err != nil {
+			return nil, err
+		}
+		data = binary.BigEndian.AppendUint16(data, uint16(len(rdata)))
+		data = append(data, rdata...)
+	}
+	return data, nil
+}
+
+func appendName(data []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("invalid name: %s", name)
+			}
+			data = append(data, byte(len(label)))
+			data = append(data, label...)
+		}
+	}
+	return append(data, 0), nil
+}
+
+func packData(record Record) ([]byte, error) {
+	switch record.Type {
+	case TypeA, TypeAAAA:
+		ip := net.ParseIP(record.Value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid address: %s", record.Value)
+		}
+		if record.Type == TypeA {
+			if ip = ip.To4(); ip == nil {
+				return nil, fmt.Errorf("not an IPv4 address: %s", record.Value)
+			}
+		}
+		return ip.To16()[16-addressLength(record.Type):], nil
+	case TypeCNAME, TypeNS:
+		return appendName(nil, record.Value)
+	case TypeMX:
+		preference, exchange, _ := strings.Cut(record.Value, " ")
+		value, err := strconv.ParseUint(preference, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MX record: %s", record.Value)
+		}
+		return appendName(binary.BigEndian.AppendUint16(nil, uint16(value)), exchange)
+	case TypeTXT:
+		data := make([]byte, 0, len(record.Value)+1)
+		text := record.Value
+		for {
+			chunk := text[:min(len(text), 255)]
+			data = append(append(data, byte(len(chunk))), chunk...)
+			text = text[len(chunk):]
+			if text == "" {
+				return data, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported record type: %s", record.Type)
+	}
+}
+
+func addressLength(recordType Type) int {
+	if recordType == TypeA {
+		return net.IPv4len
+	}
+	return net.IPv6len
+}
+
+var errShortMessage = errors.New("short DNS message")
+
+// Unpack decodes the message, the authority and additional sections are skipped.
+func Unpack(data []byte) (Message, error) {
+	if len(data) < 12 {
+		return Message{}, errShortMessage
+	}
+	flags := binary.BigEndian.Uint16(data[2:])
+	message := Message{
+		ID:                 binary.BigEndian.Uint16(data[0:]),
+		Response:           flags&(1<<15) != 0,
+		Truncated:          flags&(1<<9) != 0,
+		RecursionDesired:   flags&(1<<8) != 0,
+		RecursionAvailable: flags&(1<<7) != 0,
+		RCode:              RCode(flags & 0xF),
+	}
+	questions := int(binary.BigEndian.Uint16(data[4:]))
+	answers := int(binary.BigEndian.Uint16(data[6:]))
+
+	offset := 12
+	for range questions {
+		name, next, err := readName(data, offset)
+		if err != nil {
+			return Message{}, err
+		}
+		if next+4 > len(data) {
+			return Message{}, errShortMessage
+		}
+		message.Questions = append(message.Questions, Question{Name: name, Type: Type(binary.BigEndian.Uint16(data[next:]))})
+		offset = next + 4
+	}
+	for range answers {
+		name, next, err := readName(data, offset)
+		if err != nil {
+			return Message{}, err
+		}
+		if next+10 > len(data) {
+			return Message{}, errShortMessage
+		}
+		record := Record{
+			Name: name,
+			Type: Type(binary.BigEndian.Uint16(data[next:])),
+			TTL:  binary.BigEndian.Uint32(data[next+4:]),
+		}
+		length := int(binary.BigEndian.Uint16(data[next+8:]))
+		start := next + 10
+		if start+length > len(data) {
+			return Message{}, errShortMessage
+		}
+		if record.Value, err = unpackData(data, start, length, record.Type); err != nil {
+			return Message{}, err
+		}
+		message.Answers = append(message.Answers, record)
+		offset = start + length
+	}
+	return message, nil
+}
+
+func unpackData(data []byte, start int, length int, recordType Type) (string, error) {
+	rdata := data[start : start+length]
+	switch recordType {
+	case TypeA, TypeAAAA:
+		if length != addressLength(recordType) {
+			return "", fmt.Errorf("invalid %s record length %d", recordType, length)
+		}
+		return net.IP(rdata).String(), nil
+	case TypeCNAME, TypeNS:
+		name, _, err := readName(data, start)
+		return name, err
+	case TypeMX:
+		if length < 3 {
+			return "", errShortMessage
+		}
+		name, _, err := readName(data, start+2)
+		return fmt.Sprintf("%d %s", binary.BigEndian.Uint16(rdata), name), err
+	case TypeTXT:
+		var text strings.Builder
+		for i := 0; i < len(rdata); {
+			size := int(rdata[i])
+			if i+1+size > len(rdata) {
+				return "", errShortMessage
+			}
+			text.Write(rdata[i+1 : i+1+size])
+			i += 1 + size
+		}
+		return text.String(), nil
+	default:
+		// Other types are kept without their data
+		return "", nil
+	}
+}
+
+// readName decodes the name at offset and returns it with the offset after it.
+func readName(data []byte, offset int) (string, int, error) {
+	labels := make([]string, 0, 4)
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(data) {
+			return "", 0, errShortMessage
+		}
+		length := int(data[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(data) {
+				return "", 0, errShortMessage
+			}
+			if jumps++; jumps > 10 {
+				return "", 0, errors.New("too many compression pointers in DNS message")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(data[offset:]) & 0x3FFF)
+		default:
+			if offset+1+length > len(data) {
+				return "", 0, errShortMessage
+			}
+			labels = append(labels, string(data[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Package escalation
+//
+// Manager keeps notifying about targets which stay DOWN. Following the policy of the
+// target it re-sends the notification every RepeatInterval and escalates to further
+// channels after EscalateAfter, until the target is UP again or somebody acknowledges
+// the outage through the control API.
+
+package escalation
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const DefaultCheckInterval = 10 * time.Second
+
+// Policy decides how targets staying DOWN are re-notified.
+type Policy struct {
+	Name           string
+	Targets        []string // URLs the policy applies to, "*" for all targets
+	Tags           []string // Targets with any of these tags use the policy too
+	RepeatInterval time.Duration
+	EscalateAfter  time.Duration        // Zero never escalates
+	Escalation     *notifier.Dispatcher // Channels notified after EscalateAfter
+}
+
+// Outage is a DOWN target followed by a policy.
+type Outage struct {
+	Target         string    `json:"target"`
+	Policy         string    `json:"policy"`
+	Since          time.Time `json:"since"`
+	Reminders      int       `json:"reminders"`
+	Escalated      bool      `json:"escalated"`
+	Acknowledged   bool      `json:"acknowledged"`
+	AcknowledgedBy string    `json:"acknowledged_by,omitempty"`
+	Comment        string    `json:"comment,omitempty"`
+
+	policy       *Policy
+	event        notifier.Event // Notification of the target going DOWN
+	lastNotified time.Time
+}
+
+type Manager struct {
+	policies   []*Policy
+	tags       map[string][]string
+	dispatcher *notifier.Dispatcher // Channels of the original notifications, optional
+	interval   time.Duration
+
+	mu      sync.Mutex
+	outages map[string]*Outage
+}
+
+// NewManager creates the manager checking the outages every interval.
+// The first policy matching a target applies, by its URL or tags.
+func NewManager(policies []*Policy, tags map[string][]string, interval time.Duration) *Manager {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	if tags == nil {
+		tags = make(map[string][]string)
+	}
+	return &Manager{
+		policies: policies,
+		tags:     tags,
+		interval: interval,
+		outages:  make(map[string]*Outage),
+	}
+}
+
+func (m *Manager) WithDispatcher(dispatcher *notifier.Dispatcher) *Manager {
+	m.dispatcher = dispatcher
+	return m
+}
+
+// WithMute mutes the escalation channels, eg. for targets under maintenance.
+func (m *Manager) WithMute(mute func(notifier.Event) (bool, string)) *Manager {
+	for _, policy := range m.policies {
+		if policy.Escalation != nil {
+			policy.Escalation.WithMute(mute)
+		}
+	}
+	return m
+}
+
+// Close delivers the queued escalations and stops the escalation channels.
+func (m *Manager) Close() {
+	for _, policy := range m.policies {
+		if policy.Escalation != nil {
+			policy.Escalation.Close()
+		}
+	}
+}
+
+// Observe follows the state changes notified for the targets.
+func (m *Manager) Observe(event notifier.Event) {
+	if event.Alert != nil || event.Flap != nil || event.Escalation != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outage, tracked := m.outages[event.Target]
+	switch {
+	case event.NewState == model.StateDown && !tracked:
+		policy := m.policy(event.Target)
+		if policy == nil {
+			return
+		}
+		m.outages[event.Target] = &Outage{
+			Target:       event.Target,
+			Policy:       policy.Name,
+			Since:        event.Timestamp,
+			policy:       policy,
+			event:        event,
+			lastNotified: event.Timestamp,
+		}
+	case event.NewState != model.StateDown && tracked:
+		delete(m.outages, event.Target)
+		// Let the escalation channels resolve the incident they were paged about
+		if outage.Escalated && outage.policy.Escalation != nil {
+			outage.policy.Escalation.Dispatch(event)
+		}
+	}
+}
+
+// Acknowledge stops reminders and escalation of the outage of target.
+func (m *Manager) Acknowledge(target string, by string, comment string) (Outage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outage, tracked := m.outages[target]
+	if !tracked {
+		return Outage{}, fmt.Errorf("%s is not DOWN", target)
+	}
+	outage.Acknowledged = true
+	outage.AcknowledgedBy = by
+	outage.Comment = comment
+	internal.LOGGER.Info(fmt.Sprintf("Outage of %s acknowledged by %s: %s", target, by, comment))
+	return *outage, nil
+}
+
+// Outages returns the followed outages ordered by target.
+func (m *Manager) Outages() []Outage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	outages := make([]Outage, 0, len(m.outages))
+	for _, outage := range m.outages {
+		outages = append(outages, *outage)
+	}
+	slices.SortFunc(outages, func(a, b Outage) int {
+		return cmp.Compare(a.Target, b.Target)
+	})
+	return outages
+}
+
+// Run checks the outages every interval until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.Check(now)
+		}
+	}
+}
+
+// Check sends the reminders and escalations due at the time now and returns them.
+func (m *Manager) Check(now time.Time) []notifier.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := make([]notifier.Event, 0)
+	for _, outage := range m.outages {
+		if outage.Acknowledged {
+			continue
+		}
+		policy := outage.policy
+		if !outage.Escalated && policy.EscalateAfter > 0 && now.Sub(outage.Since) >= policy.EscalateAfter {
+			outage.Escalated = true
+			event := outage.newEvent(now, 0)
+			if policy.Escalation != nil {
+				policy.Escalation.Dispatch(event)
+			}
+			events = append(events, event)
+		}
+		if policy.RepeatInterval > 0 && now.Sub(outage.lastNotified) >= policy.RepeatInterval {
+			outage.Reminders++
+			outage.lastNotified = now
+			event := outage.newEvent(now, outage.Reminders)
+			if m.dispatcher != nil {
+				m.dispatcher.Dispatch(event)
+			}
+			if outage.Escalated && policy.Escalation != nil {
+				policy.Escalation.Dispatch(event)
+			}
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (m *Manager) policy(target string) *Policy {
+	for _, policy := range m.policies {
+		if slices.Contains(policy.Targets, target) || slices.Contains(policy.Targets, "*") {
+			return policy
+		}
+		for _, tag := range m.tags[target] {
+			if slices.Contains(policy.Tags, tag) {
+				return policy
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Outage) newEvent(now time.Time, reminder int) notifier.Event {
+	event := o.event
+	event.Timestamp = now
+	event.Escalation = &notifier.Escalation{
+		Policy:    o.Policy,
+		Reminder:  reminder,
+		Escalated: reminder == 0 || o.Escalated,
+		DownFor:   now.Sub(o.Since),
+	}
+	return event
+}
@@ -0,0 +1,161 @@
+// Package maintenance
+//
+// Manager knows when targets are under maintenance: during scheduled maintenance
+// windows (absolute ranges or cron-like recurring ones) and ad-hoc silences created
+// through the control API. Checks of such targets still run and are recorded, but
+// their results are flagged, notifications are muted and uptime ignores them.
+
+package maintenance
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Window is a scheduled maintenance window. It's either an absolute range [Start, End)
+// or a recurring one, lasting Duration from every trigger of Schedule (evaluated in UTC).
+type Window struct {
+	Name     string
+	Targets  []string // URLs in maintenance, "*" for all targets
+	Tags     []string // Targets with any of these tags are in maintenance too
+	Start    time.Time
+	End      time.Time
+	Schedule *Schedule
+	Duration time.Duration
+}
+
+// Active reports whether the window covers the time t.
+func (w Window) Active(t time.Time) bool {
+	if w.Schedule != nil {
+		_, triggered := w.Schedule.LastTrigger(t.UTC(), w.Duration-time.Nanosecond)
+		return triggered
+	}
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Silence is an ad-hoc maintenance of some targets, eg. during a deploy.
+type Silence struct {
+	ID      string    `json:"id"`
+	Targets []string  `json:"targets,omitempty"`
+	Tags    []string  `json:"tags,omitempty"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+func (s Silence) Active(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+type Manager struct {
+	mu       sync.Mutex
+	windows  []Window
+	silences []Silence
+	tags     map[string][]string // Tags of the targets
+	nextID   int
+}
+
+// NewManager creates the manager with the scheduled windows and the tags of the targets.
+func NewManager(windows []Window, tags map[string][]string) *Manager {
+	if tags == nil {
+		tags = make(map[string][]string)
+	}
+	return &Manager{
+		windows:  windows,
+		silences: make([]Silence, 0),
+		tags:     tags,
+		nextID:   1,
+	}
+}
+
+// InMaintenance reports whether url is under maintenance at the time t
+// and the name of the window or the ID of the silence causing it.
+// Silences expired at the time t are forgotten.
+func (m *Manager) InMaintenance(url string, t time.Time) (bool, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(t)
+
+	for _, window := range m.windows {
+		if m.applies(url, window.Targets, window.Tags) && window.Active(t) {
+			return true, "window " + window.Name
+		}
+	}
+	for _, silence := range m.silences {
+		if m.applies(url, silence.Targets, silence.Tags) && silence.Active(t) {
+			return true, "silence " + silence.ID
+		}
+	}
+	return false, ""
+}
+
+// AddSilence validates the silence and assigns it an ID. Zero Start means now.
+// Silences expired by now are forgotten.
+func (m *Manager) AddSilence(silence Silence) (Silence, error) {
+	if len(silence.Targets) == 0 && len(silence.Tags) == 0 {
+		return Silence{}, fmt.Errorf("silence requires targets or tags")
+	}
+	if silence.Start.IsZero() {
+		silence.Start = time.Now().UTC()
+	}
+	if !silence.End.After(silence.Start) {
+		return Silence{}, fmt.Errorf("silence must end after it starts")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(time.Now())
+	silence.ID = strconv.Itoa(m.nextID)
+	m.nextID++
+	m.silences = append(m.silences, silence)
+	return silence, nil
+}
+
+// RemoveSilence ends the silence early, false if there is no such silence.
+func (m *Manager) RemoveSilence(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, silence := range m.silences {
+		if silence.ID == id {
+			m.silences = slices.Delete(m.silences, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// Silences returns the silences not expired at the time now, ordered by start.
+// Expired silences are forgotten.
+func (m *Manager) Silences(now time.Time) []Silence {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune(now)
+	silences := append([]Silence{}, m.silences...)
+	sort.SliceStable(silences, func(i, j int) bool {
+		return silences[i].Start.Before(silences[j].Start)
+	})
+	return silences
+}
+
+// prune forgets the silences expired at the time now, the caller holds the lock.
+func (m *Manager) prune(now time.Time) {
+	m.silences = slices.DeleteFunc(m.silences, func(silence Silence) bool {
+		return !now.Before(silence.End)
+	})
+}
+
+func (m *Manager) applies(url string, targets []string, tags []string) bool {
+	if slices.Contains(targets, url) || slices.Contains(targets, "*") {
+		return true
+	}
+	for _, tag := range m.tags[url] {
+		if slices.Contains(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron-like schedule with the standard five fields:
+// minute, hour, day of month, month and day of week (0 is Sunday), eg. "0 2 * * 0"
+// for every Sunday at 02:00. Fields accept *, values, ranges, lists and steps (*/15, 1-5).
+type Schedule struct {
+	spec    string
+	minutes [60]bool
+	hours   [24]bool
+	days    [32]bool
+	months  [13]bool
+	weekday [7]bool
+	// Like cron, a restricted day of month and day of week match when either of them does
+	anyDay     bool
+	anyWeekday bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+func ParseSchedule(spec string) (*Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("invalid schedule %q, expected 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+	schedule := &Schedule{
+		spec:       spec,
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}
+	targets := [][]bool{schedule.minutes[:], schedule.hours[:], schedule.days[:], schedule.months[:], schedule.weekday[:]}
+	for i, part := range parts {
+		if err := parseField(part, fields[i], targets[i]); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	return schedule, nil
+}
+
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Matches reports whether the schedule triggers in the minute of t.
+func (s *Schedule) Matches(t time.Time) bool {
+	return s.minutes[t.Minute()] && s.hours[t.Hour()] && s.matchesDay(t)
+}
+
+// matchesDay reports whether the schedule triggers on the day of t.
+func (s *Schedule) matchesDay(t time.Time) bool {
+	if !s.months[t.Month()] {
+		return false
+	}
+	day, weekday := s.days[t.Day()], s.weekday[t.Weekday()]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// LastTrigger returns the latest time at or before t the schedule triggered,
+// looking back at most lookback. ok is false if it didn't trigger within lookback.
+// It steps back through the matching days, hours and minutes, so a long lookback
+// costs one step per day rather than one per minute.
+func (s *Schedule) LastTrigger(t time.Time, lookback time.Duration) (time.Time, bool) {
+	latest := t.Truncate(time.Minute)
+	earliest := t.Add(-lookback)
+	firstDay := time.Date(earliest.Year(), earliest.Month(), earliest.Day(), 0, 0, 0, 0, t.Location())
+	for day := time.Date(latest.Year(), latest.Month(), latest.Day(), 0, 0, 0, 0, t.Location()); !day.Before(firstDay); day = day.AddDate(0, 0, -1) {
+		if !s.matchesDay(day) {
+			continue
+		}
+		lastHour, lastMinute := 23, 59
+		if day.Day() == latest.Day() && day.Month() == latest.Month() && day.Year() == latest.Year() {
+			lastHour, lastMinute = latest.Hour(), latest.Minute()
+		}
+		for hour := lastHour; hour >= 0; hour-- {
+			if !s.hours[hour] {
+				continue
+			}
+			minute := 59
+			if hour == lastHour {
+				minute = lastMinute
+			}
+			for ; minute >= 0; minute-- {
+				if !s.minutes[minute] {
+					continue
+				}
+				trigger := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, t.Location())
+				if trigger.After(latest) {
+					// A minute skipped by a DST change, normalized past t
+					continue
+				}
+				if trigger.Before(earliest) {
+					return time.Time{}, false
+				}
+				return trigger, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseField(value string, f field, target []bool) error {
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			parsed, err := strconv.Atoi(stepPart)
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid step %q in %s", stepPart, f.name)
+			}
+			step = parsed
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return fmt.Errorf("invalid %s %q", f.name, item)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(to); err != nil {
+					return fmt.Errorf("invalid %s %q", f.name, item)
+				}
+			} else if hasStep {
+				high = f.max
+			}
+		}
+		if low < f.min || high > f.max || low > high {
+			return fmt.Errorf("%s %q out of range %d-%d", f.name, item, f.min, f.max)
+		}
+		for i := low; i <= high; i += step {
+			target[i] = true
+		}
+	}
+	return nil
+}
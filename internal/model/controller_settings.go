@@ -12,6 +12,11 @@ type AppSettings struct {
 	Context         context.Context
 	OutputStream    io.Writer
 	MaxQueueSize    int
+	Fall            int           // consecutive failures before a target becomes DOWN
+	Rise            int           // consecutive successes before a target becomes UP again
+	FlapWindow      time.Duration // window in which state changes are counted for flap detection
+	FlapThreshold   int           // state changes within FlapWindow above which a target is FLAPPING, 0 disables
+	Targets         map[string]TargetSettings
 }
 
 func NewAppSettings() *AppSettings {
@@ -19,6 +24,9 @@ func NewAppSettings() *AppSettings {
 		Timeout:         10 * time.Second, // default timeout
 		PollingInterval: 5 * time.Second,  // default polling interval
 		MaxQueueSize:    5,                // default max queue size
+		Fall:            1,                // default fall count, first failure flips to DOWN
+		Rise:            1,                // default rise count, first success flips to UP
+		Targets:         make(map[string]TargetSettings),
 	}
 }
 
@@ -46,3 +54,35 @@ func (s *AppSettings) WithMaxQueueSize(size int) *AppSettings {
 	s.MaxQueueSize = size
 	return s
 }
+
+func (s *AppSettings) WithFallRise(fall int, rise int) *AppSettings {
+	s.Fall = fall
+	s.Rise = rise
+	return s
+}
+
+func (s *AppSettings) WithFlapDetection(window time.Duration, threshold int) *AppSettings {
+	s.FlapWindow = window
+	s.FlapThreshold = threshold
+	return s
+}
+
+func (s *AppSettings) WithTargetSettings(url string, settings TargetSettings) *AppSettings {
+	if s.Targets == nil {
+		s.Targets = make(map[string]TargetSettings)
+	}
+	s.Targets[url] = settings
+	return s
+}
+
+// TargetSettings returns the settings of url with the defaults filled in.
+func (s *AppSettings) TargetSettings(url string) TargetSettings {
+	settings := s.Targets[url]
+	if settings.Fall <= 0 {
+		settings.Fall = max(s.Fall, 1)
+	}
+	if settings.Rise <= 0 {
+		settings.Rise = max(s.Rise, 1)
+	}
+	return settings
+}
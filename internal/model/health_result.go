@@ -1,16 +1,48 @@
 package model
 
 import (
+	"fmt"
 	"time"
 )
 
 type HealthCheckResult struct {
-	StatusCode int           `json:"status_code"` // HTTP status code (0 if network error)
-	Latency    time.Duration `json:"latency"`     // Request duration
-	Timestamp  time.Time     `json:"timestamp"`   // When check occurred
-	IsOk       bool          `json:"isOk"`        // Is the URL healthy
-	Size       uint64        `json:"size"`        // Size of the response
-	Error      error         `json:"-"`           // Error if any occurred during the check
+	StatusCode int           `json:"status_code"`          // HTTP status code (0 if network error or not HTTP)
+	Latency    time.Duration `json:"latency"`              // Request duration
+	Timestamp  time.Time     `json:"timestamp"`            // When check occurred
+	IsOk       bool          `json:"isOk"`                 // Is the URL healthy
+	Size       uint64        `json:"size"`                 // Size of the response
+	Error      error         `json:"-"`                    // Error if any occurred during the check
+	State      State         `json:"state,omitempty"`      // Effective state after applying fall/rise thresholds
+	CertExpiry time.Time     `json:"cert_expiry,omitzero"` // Expiry of the server certificate, zero without TLS
+	Flapping   bool          `json:"flapping,omitempty"`   // The target changes its state too often
+	// The target was under maintenance, the result doesn't count into uptime and incidents
+	Maintenance bool `json:"maintenance,omitempty"`
+	// Time from sending a message to its reply, for checks exchanging messages after connecting
+	RoundTrip time.Duration `json:"round_trip,omitempty"`
+	// State reported by checks with more outcomes than passed and failed: DEGRADED (passed) or UNKNOWN (inconclusive)
+	CheckState State `json:"check_state,omitempty"`
+	// First line of the output of exec checks, without the performance data
+	Output   string     `json:"output,omitempty"`
+	Perfdata []Perfdata `json:"perfdata,omitempty"`
+	// Steps of checks holding a conversation with the server, eg. the greeting and STARTTLS of mail servers
+	Phases []Phase `json:"phases,omitempty"`
+}
+
+// Phase is a step of a check and how long it took.
+type Phase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Perfdata is a metric reported by a Nagios plugin, eg. /=2643MB;5948;5958;0;5968.
+type Perfdata struct {
+	Label    string  `json:"label"`
+	Value    float64 `json:"value"`
+	Unit     string  `json:"unit,omitempty"`
+	Warning  string  `json:"warning,omitempty"`  // Range, eg. 10:20
+	Critical string  `json:"critical,omitempty"` // Range, eg. @10:20
+	Min      string  `json:"min,omitempty"`
+	Max      string  `json:"max,omitempty"`
 }
 
 func NewHealthCheckResult(
@@ -29,6 +61,16 @@ func NewHealthCheckResult(
 	}
 }
 
+// NewSuccessfulResult creates the result of a passed check of a protocol without HTTP status codes, eg. TCP.
+func NewSuccessfulResult(latency time.Duration, sizeOfResponse uint64) HealthCheckResult {
+	return HealthCheckResult{
+		IsOk:      true,
+		Latency:   latency,
+		Timestamp: time.Now().UTC(),
+		Size:      sizeOfResponse,
+	}
+}
+
 func NewHealthCheckResultWithError(err error, latency time.Duration) HealthCheckResult {
 	return HealthCheckResult{
 		IsOk:      false,
@@ -37,3 +79,32 @@ func NewHealthCheckResultWithError(err error, latency time.Duration) HealthCheck
 		Timestamp: time.Now().UTC(),
 	}
 }
+
+// Inconclusive reports whether the check couldn't tell the state of the target, eg. exit code 3
+// of a Nagios plugin. Like the results during maintenance, it doesn't count into uptime and incidents.
+func (r HealthCheckResult) Inconclusive() bool {
+	return r.CheckState == StateUnknown
+}
+
+// Healthy returns the effective state if it was computed, the raw outcome of the check otherwise.
+func (r HealthCheckResult) Healthy() bool {
+	if r.State != "" && r.State != StateUnknown {
+		return r.State == StateUp || r.State == StateDegraded
+	}
+	return r.IsOk
+}
+
+// ErrorMessage describes why the check failed: the error if any occurred, the status code of HTTP checks,
+// otherwise the output of the check.
+func (r HealthCheckResult) ErrorMessage() string {
+	if r.Error != nil {
+		return r.Error.Error()
+	}
+	if r.StatusCode != 0 {
+		return fmt.Sprintf("HTTP %d", r.StatusCode)
+	}
+	if r.Output != "" {
+		return r.Output
+	}
+	return "check failed"
+}
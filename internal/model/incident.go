@@ -0,0 +1,116 @@
+package model
+
+import (
+	"time"
+)
+
+// Incident is a continuous period during which a URL was failing.
+type Incident struct {
+	Start        time.Time     `json:"start"`
+	End          time.Time     `json:"end"`      // Zero while the incident is ongoing
+	Duration     time.Duration `json:"duration"` // Up to the last check for ongoing incidents
+	FirstError   string        `json:"first_error"`
+	FailedChecks int           `json:"failed_checks"`
+}
+
+func (i Incident) Ongoing() bool {
+	return i.End.IsZero()
+}
+
+// IncidentTracker detects UP/DOWN transitions in the stream of results of one URL
+// and records the outages as incidents.
+type IncidentTracker struct {
+	incidents []Incident
+	firstSeen time.Time
+	lastSeen  time.Time
+
+	streak   int
+	streakOk bool
+}
+
+func NewIncidentTracker() *IncidentTracker {
+	return &IncidentTracker{incidents: make([]Incident, 0)}
+}
+
+func (t *IncidentTracker) Observe(result HealthCheckResult) {
+	if t.firstSeen.IsZero() {
+		t.firstSeen = result.Timestamp
+	}
+	t.lastSeen = result.Timestamp
+	if result.Maintenance || result.Inconclusive() {
+		// Expected failures and inconclusive checks neither open nor close incidents
+		return
+	}
+
+	ok := result.Healthy()
+	if t.streak > 0 && t.streakOk == ok {
+		t.streak++
+	} else {
+		t.streak = 1
+		t.streakOk = ok
+	}
+
+	current := t.current()
+	switch {
+	case !ok && current == nil:
+		t.incidents = append(t.incidents, Incident{
+			Start:        result.Timestamp,
+			FirstError:   result.ErrorMessage(),
+			FailedChecks: 1,
+		})
+	case !ok:
+		current.FailedChecks++
+		current.Duration = result.Timestamp.Sub(current.Start)
+	case current != nil:
+		current.End = result.Timestamp
+		current.Duration = current.End.Sub(current.Start)
+	}
+}
+
+// Incidents returns a copy of all incidents, oldest first.
+func (t *IncidentTracker) Incidents() []Incident {
+	incidents := make([]Incident, len(t.incidents))
+	copy(incidents, t.incidents)
+	return incidents
+}
+
+// Summarize fills the incident related fields of metrics.
+// MTTR is the mean duration of resolved incidents, MTBF is the observed uptime
+// divided by the number of incidents.
+func (t *IncidentTracker) Summarize(metrics *Metrics) {
+	metrics.Incidents = len(t.incidents)
+	metrics.CurrentStreak = t.streak
+	metrics.CurrentStreakOk = t.streakOk
+	metrics.MTTR = 0
+	metrics.MTBF = 0
+	metrics.LongestOutage = 0
+
+	var downtime, repairTime time.Duration
+	resolved := 0
+	for _, incident := range t.incidents {
+		downtime += incident.Duration
+		if incident.Duration > metrics.LongestOutage {
+			metrics.LongestOutage = incident.Duration
+		}
+		if !incident.Ongoing() {
+			repairTime += incident.Duration
+			resolved++
+		}
+	}
+	if resolved > 0 {
+		metrics.MTTR = repairTime / time.Duration(resolved)
+	}
+	if len(t.incidents) > 0 {
+		uptime := t.lastSeen.Sub(t.firstSeen) - downtime
+		if uptime > 0 {
+			metrics.MTBF = uptime / time.Duration(len(t.incidents))
+		}
+	}
+}
+
+func (t *IncidentTracker) current() *Incident {
+	if len(t.incidents) == 0 || !t.incidents[len(t.incidents)-1].Ongoing() {
+		return nil
+	}
+	return &t.incidents[len(t.incidents)-1]
+}
@@ -1,9 +1,15 @@
 package model
 
+import "time"
+
 type Metrics struct {
 	TotalRequests   int `json:"total_requests"`
 	FailedRequests  int `json:"failed_requests"`
 	SuccessRequests int `json:"success_requests"`
+	// Checks during maintenance, not counted in the other statistics
+	MaintenanceRequests int `json:"maintenance_requests,omitempty"`
+	// Inconclusive checks, eg. plugins exiting with UNKNOWN, not counted in the other statistics
+	UnknownRequests int `json:"unknown_requests,omitempty"`
 
 	LatencyAverage float64 `json:"latency_average"`
 	LatencyMin     float64 `json:"latency_min"`
@@ -12,34 +18,40 @@ type Metrics struct {
 	SizeAverage uint64 `json:"size_average"`
 	SizeMin     uint64 `json:"size_min"`
 	SizeMax     uint64 `json:"size_max"`
+
+	// Outage statistics, filled from the IncidentTracker of the URL
+	Incidents       int           `json:"incidents"`
+	MTTR            time.Duration `json:"mttr"`
+	MTBF            time.Duration `json:"mtbf"`
+	LongestOutage   time.Duration `json:"longest_outage"`
+	CurrentStreak   int           `json:"current_streak"`    // Number of consecutive checks with the same outcome
+	CurrentStreakOk bool          `json:"current_streak_ok"` // Whether the current streak is successful
 }
 
 func NewMetrics(result HealthCheckResult) Metrics {
-	// Convert values to float64
-	latency := float64(result.Latency.Milliseconds())
-	metrics := Metrics{
-		TotalRequests:   1,
-		FailedRequests:  0,
-		SuccessRequests: 0,
-		LatencyAverage:  latency,
-		LatencyMin:      latency,
-		LatencyMax:      latency,
-		SizeAverage:     result.Size,
-		SizeMin:         result.Size,
-		SizeMax:         result.Size,
-	}
-
-	// Set success/failure count based on the result
-	if result.IsOk {
-		metrics.SuccessRequests = 1
-	} else {
-		metrics.FailedRequests = 1
-	}
-
+	metrics := Metrics{}
+	metrics.Update(result)
 	return metrics
 }
 
 func (m *Metrics) Update(result HealthCheckResult) {
+	if result.Maintenance {
+		m.MaintenanceRequests++
+		return
+	}
+	if result.Inconclusive() {
+		m.UnknownRequests++
+		return
+	}
+
+	// Convert values to float64
+	latency := float64(result.Latency.Milliseconds())
+	size := result.Size
+	if m.TotalRequests == 0 {
+		m.LatencyMin, m.LatencyMax = latency, latency
+		m.SizeMin, m.SizeMax = size, size
+	}
+
 	// Update counters
 	m.TotalRequests++
 	if result.IsOk {
@@ -48,10 +60,6 @@ func (m *Metrics) Update(result HealthCheckResult) {
 		m.FailedRequests++
 	}
 
-	// Convert values to float64
-	latency := float64(result.Latency.Milliseconds())
-	size := result.Size
-
 	// Update latency statistics
 	m.LatencyAverage = ((m.LatencyAverage * float64(m.TotalRequests-1)) + latency) / float64(m.TotalRequests)
 	if latency < m.LatencyMin {
@@ -70,3 +78,43 @@ func (m *Metrics) Update(result HealthCheckResult) {
 		m.SizeMax = size
 	}
 }
+
+// Merge folds an already aggregated bucket into the metrics,
+// used when rebuilding metrics from downsampled history.
+func (m *Metrics) Merge(bucket RollupBucket) {
+	m.MaintenanceRequests += bucket.Maintenance
+	m.UnknownRequests += bucket.Unknown
+	if bucket.Count == 0 {
+		return
+	}
+	if m.TotalRequests == 0 {
+		m.LatencyMin = bucket.LatencyMin
+		m.LatencyMax = bucket.LatencyMax
+		m.SizeMin = bucket.SizeMin
+		m.SizeMax = bucket.SizeMax
+	}
+
+	previous := float64(m.TotalRequests)
+	m.TotalRequests += bucket.Count
+	m.FailedRequests += bucket.Failures
+	m.SuccessRequests += bucket.Count - bucket.Failures
+	total := float64(m.TotalRequests)
+
+	// Update latency statistics
+	m.LatencyAverage = (m.LatencyAverage*previous + bucket.LatencyAverage*float64(bucket.Count)) / total
+	if bucket.LatencyMin < m.LatencyMin {
+		m.LatencyMin = bucket.LatencyMin
+	}
+	if bucket.LatencyMax > m.LatencyMax {
+		m.LatencyMax = bucket.LatencyMax
+	}
+
+	// Update size statistics
+	m.SizeAverage = uint64((float64(m.SizeAverage)*previous + float64(bucket.SizeAverage)*float64(bucket.Count)) / total)
+	if bucket.SizeMin < m.SizeMin {
+		m.SizeMin = bucket.SizeMin
+	}
+	if bucket.SizeMax > m.SizeMax {
+		m.SizeMax = bucket.SizeMax
+	}
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// RollupBucket aggregates all results of one URL within [Start, Start+Resolution).
+// Latencies are in milliseconds, same as in Metrics.
+type RollupBucket struct {
+	Start      time.Time     `json:"start"`
+	Resolution time.Duration `json:"resolution"`
+
+	Count       int `json:"count"`
+	Failures    int `json:"failures"`
+	Maintenance int `json:"maintenance,omitempty"` // Results during maintenance, not part of Count and the statistics
+	Unknown     int `json:"unknown,omitempty"`     // Inconclusive results, not part of Count and the statistics
+
+	LatencyMin     float64 `json:"latency_min"`
+	LatencyMax     float64 `json:"latency_max"`
+	LatencyAverage float64 `json:"latency_average"`
+	LatencyP50     float64 `json:"latency_p50"`
+	LatencyP95     float64 `json:"latency_p95"`
+	LatencyP99     float64 `json:"latency_p99"`
+
+	SizeMin     uint64 `json:"size_min"`
+	SizeMax     uint64 `json:"size_max"`
+	SizeAverage uint64 `json:"size_average"`
+}
+
+// NewRollupBucket aggregates results, which must all fall into the bucket starting at start.
+func NewRollupBucket(start time.Time, resolution time.Duration, results []HealthCheckResult) RollupBucket {
+	bucket := RollupBucket{
+		Start:      start,
+		Resolution: resolution,
+	}
+	counted := make([]HealthCheckResult, 0, len(results))
+	for _, result := range results {
+		switch {
+		case result.Maintenance:
+			bucket.Maintenance++
+		case result.Inconclusive():
+			bucket.Unknown++
+		default:
+			counted = append(counted, result)
+		}
+	}
+	results = counted
+	bucket.Count = len(results)
+	if len(results) == 0 {
+		return bucket
+	}
+
+	latencies := make([]float64, 0, len(results))
+	latencySum := 0.0
+	sizeSum := 0.0
+	bucket.SizeMin = math.MaxUint64
+	for _, result := range results {
+		if !result.IsOk {
+			bucket.Failures++
+		}
+		latency := float64(result.Latency.Milliseconds())
+		latencies = append(latencies, latency)
+		latencySum += latency
+
+		sizeSum += float64(result.Size)
+		if result.Size < bucket.SizeMin {
+			bucket.SizeMin = result.Size
+		}
+		if result.Size > bucket.SizeMax {
+			bucket.SizeMax = result.Size
+		}
+	}
+	sort.Float64s(latencies)
+
+	bucket.LatencyMin = latencies[0]
+	bucket.LatencyMax = latencies[len(latencies)-1]
+	bucket.LatencyAverage = latencySum / float64(len(results))
+	bucket.LatencyP50 = Percentile(latencies, 50)
+	bucket.LatencyP95 = Percentile(latencies, 95)
+	bucket.LatencyP99 = Percentile(latencies, 99)
+	bucket.SizeAverage = uint64(sizeSum / float64(len(results)))
+	return bucket
+}
+
+// Merge folds another bucket of the same URL and start into b, eg. late results of an already compacted day.
+// The percentiles of merged buckets are count-weighted averages, the raw latencies are gone by then.
+func (b *RollupBucket) Merge(other RollupBucket) {
+	b.Maintenance += other.Maintenance
+	b.Unknown += other.Unknown
+	if other.Count == 0 {
+		return
+	}
+	if b.Count == 0 {
+		b.LatencyMin, b.LatencyMax = other.LatencyMin, other.LatencyMax
+		b.SizeMin, b.SizeMax = other.SizeMin, other.SizeMax
+	}
+
+	weight := func(value, otherValue float64) float64 {
+		return (value*float64(b.Count) + otherValue*float64(other.Count)) / float64(b.Count+other.Count)
+	}
+	b.LatencyAverage = weight(b.LatencyAverage, other.LatencyAverage)
+	b.LatencyP50 = weight(b.LatencyP50, other.LatencyP50)
+	b.LatencyP95 = weight(b.LatencyP95, other.LatencyP95)
+	b.LatencyP99 = weight(b.LatencyP99, other.LatencyP99)
+	b.SizeAverage = uint64(weight(float64(b.SizeAverage), float64(other.SizeAverage)))
+	b.LatencyMin = math.Min(b.LatencyMin, other.LatencyMin)
+	b.LatencyMax = math.Max(b.LatencyMax, other.LatencyMax)
+	b.SizeMin = min(b.SizeMin, other.SizeMin)
+	b.SizeMax = max(b.SizeMax, other.SizeMax)
+
+	b.Count += other.Count
+	b.Failures += other.Failures
+}
+
+// Percentile returns the nearest-rank percentile of already sorted values.
+func Percentile(sorted []float64, percentile float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
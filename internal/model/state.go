@@ -0,0 +1,22 @@
+package model
+
+// State is the effective state of a target, as shown to the user and used for alerting.
+// Unlike HealthCheckResult.IsOk it's debounced by the fall/rise thresholds.
+type State string
+
+const (
+	StateUnknown State = "UNKNOWN"
+	StateUp      State = "UP"
+	StateDown    State = "DOWN"
+	// StateFlapping is shown instead of UP/DOWN while the target changes its state too often
+	StateFlapping State = "FLAPPING"
+	// StateDegraded is a check passing with a warning, eg. exit code 1 of a Nagios plugin
+	StateDegraded State = "DEGRADED"
+)
+
+// TargetSettings override the AppSettings for a single target.
+// Zero values fall back to the AppSettings.
+type TargetSettings struct {
+	Fall int // consecutive failures before the target becomes DOWN
+	Rise int // consecutive successes before the target becomes UP again
+}
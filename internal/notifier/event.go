@@ -0,0 +1,203 @@
+package notifier
+
+import (
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/state"
+	"fmt"
+	"time"
+)
+
+// HistorySize is the number of recent results attached to events.
+const HistorySize = 10
+
+// Event is a notification about a target, delivered to every configured Notifier.
+type Event struct {
+	Target     string
+	OldState   model.State
+	NewState   model.State
+	Error      string // Last error, empty when the target is healthy
+	StatusCode int
+	Latency    time.Duration
+	Timestamp  time.Time
+	// IncidentDuration is how long the target was DOWN, set on recovery
+	IncidentDuration time.Duration
+	// History holds the most recent results of the target, oldest first
+	History []model.HealthCheckResult
+	// Alert is set for events raised by alert rules, nil for state changes
+	Alert *Alert
+	// Flap is set for notices about a target which started or stopped flapping
+	Flap *Flap
+	// Escalation is set for reminders and escalations of a target which stays DOWN
+	Escalation *Escalation
+	// Group is set for state changes of several targets aggregated into one event,
+	// Target is then the key of the group
+	Group *Group
+}
+
+// Escalation describes a repeated notification about a target which is still DOWN.
+type Escalation struct {
+	Policy    string
+	Reminder  int           // Number of the reminder, 0 for the escalation itself
+	Escalated bool          // Sent to the escalation channels
+	DownFor   time.Duration // How long the target has been DOWN
+}
+
+// Flap describes a target which started or stopped flapping.
+type Flap struct {
+	Flapping bool
+	Changes  int // State changes within Window
+	Window   time.Duration
+}
+
+// Alert describes an alert rule which started or stopped firing for the target.
+type Alert struct {
+	Rule      string
+	Severity  string
+	Labels    map[string]string
+	Condition string // Evaluated expression, eg. "p95(latency, 5m) = 950ms > 800ms"
+	Firing    bool   // false when the alert is resolved
+}
+
+// NewStateChangeEvent creates an Event from a state transition of a target.
+func NewStateChangeEvent(transition state.Transition) Event {
+	event := Event{
+		Target:     transition.URL,
+		OldState:   transition.From,
+		NewState:   transition.To,
+		StatusCode: transition.Result.StatusCode,
+		Latency:    transition.Result.Latency,
+		Timestamp:  transition.Timestamp,
+	}
+	if !transition.Result.IsOk {
+		event.Error = transition.Result.ErrorMessage()
+	}
+	if transition.To == model.StateDegraded {
+		// The warning of the plugin
+		event.Error = transition.Result.Output
+	}
+	if transition.From == model.StateDown && transition.To == model.StateUp {
+		event.IncidentDuration = transition.Duration
+	}
+	return event
+}
+
+// NewFlapEvent creates the single notice sent when a target starts flapping or becomes stable again.
+func NewFlapEvent(change state.FlapChange, result model.HealthCheckResult) Event {
+	event := Event{
+		Target:     change.URL,
+		OldState:   change.Notified,
+		NewState:   model.StateFlapping,
+		StatusCode: result.StatusCode,
+		Latency:    result.Latency,
+		Timestamp:  change.Timestamp,
+		Flap:       &Flap{Flapping: change.Flapping, Changes: change.Changes, Window: change.Window},
+	}
+	if !change.Flapping {
+		event.OldState = model.StateFlapping
+		event.NewState = change.State
+	}
+	if !result.IsOk {
+		event.Error = result.ErrorMessage()
+	}
+	return event
+}
+
+// IsRecovery reports whether the event is a target coming back UP after being DOWN.
+func (e Event) IsRecovery() bool {
+	return e.OldState == model.StateDown && e.NewState == model.StateUp
+}
+
+// Resolved reports whether the event closes a problem: a target no longer DOWN or a resolved alert.
+func (e Event) Resolved() bool {
+	if e.Alert != nil {
+		return !e.Alert.Firing
+	}
+	if e.Flap != nil {
+		return !e.Flap.Flapping
+	}
+	return e.NewState != model.StateDown
+}
+
+// Pages reports whether the event opens or closes an incident of the paging services. State changes only
+// page when a target goes DOWN or leaves DOWN, so that eg. a DEGRADED warning doesn't wake anyone up.
+func (e Event) Pages() bool {
+	if e.Alert != nil || e.Flap != nil {
+		return true
+	}
+	return e.NewState == model.StateDown || e.OldState == model.StateDown
+}
+
+// DedupKey is the incident key of the event, shared by the events opening and closing the same problem.
+func (e Event) DedupKey() string {
+	if e.Alert != nil {
+		return DedupKey(e.Target + "#" + e.Alert.Rule)
+	}
+	if e.Flap != nil {
+		return DedupKey(e.Target + "#flapping")
+	}
+	return DedupKey(e.Target)
+}
+
+// Title is a short headline of the event, eg. "https://example.com is DOWN".
+func (e Event) Title() string {
+	if e.Alert != nil {
+		return fmt.Sprintf("%s %s for %s", e.Alert.Rule, e.alertStatus(), e.Target)
+	}
+	return fmt.Sprintf("%s is %s", e.Target, e.NewState)
+}
+
+// Summary is a one line human readable description of the event.
+func (e Event) Summary() string {
+	switch {
+	case e.Alert != nil && e.Alert.Firing:
+		return fmt.Sprintf("[%s] %s: %s", e.Alert.Severity, e.Title(), e.Alert.Condition)
+	case e.Alert != nil:
+		return fmt.Sprintf("%s: %s", e.Title(), e.Alert.Condition)
+	case e.Flap != nil && e.Flap.Flapping:
+		return fmt.Sprintf("%s is FLAPPING: %d state changes within %s, notifications are suppressed",
+			e.Target, e.Flap.Changes, e.Flap.Window)
+	case e.Flap != nil:
+		return fmt.Sprintf("%s is stable again and %s", e.Target, e.NewState)
+	case e.Escalation != nil && e.Escalation.Reminder == 0:
+		return fmt.Sprintf("[ESCALATED] %s is DOWN for %s: %s", e.Target, e.Escalation.DownFor.Round(time.Second), e.Error)
+	case e.Escalation != nil:
+		return fmt.Sprintf("%s is still DOWN after %s: %s (reminder %d)",
+			e.Target, e.Escalation.DownFor.Round(time.Second), e.Error, e.Escalation.Reminder)
+	case e.Group != nil && e.IsRecovery():
+		return fmt.Sprintf("All %d targets of %s are UP again after %s: %s",
+			e.Group.Affected, e.Target, e.IncidentDuration.Round(time.Second), e.Group.targetList())
+	case e.Group != nil && len(e.Group.Events) < e.Group.Affected:
+		return fmt.Sprintf("%d more targets of %s are DOWN (%d in total): %s",
+			len(e.Group.Events), e.Target, e.Group.Affected, e.Group.targetList())
+	case e.Group != nil:
+		return fmt.Sprintf("%d targets of %s are DOWN: %s", len(e.Group.Events), e.Target, e.Group.targetList())
+	case e.IsRecovery():
+		return fmt.Sprintf("%s is UP again after %s", e.Target, e.IncidentDuration.Round(time.Second))
+	case e.NewState == model.StateDown && e.Error != "":
+		return fmt.Sprintf("%s is DOWN: %s", e.Target, e.Error)
+	default:
+		return fmt.Sprintf("%s is %s", e.Target, e.NewState)
+	}
+}
+
+func (e Event) alertStatus() string {
+	if e.Alert.Firing {
+		return "FIRING"
+	}
+	return "RESOLVED"
+}
+
+// color is the message color of the event for chat formats.
+func (e Event) color() string {
+	if e.Alert == nil {
+		return stateColor(e.NewState)
+	}
+	switch {
+	case !e.Alert.Firing:
+		return colorUp
+	case e.Alert.Severity == "critical" || e.Alert.Severity == "error":
+		return colorDown
+	default:
+		return colorWarning
+	}
+}
@@ -0,0 +1,149 @@
+package notifier
+
+import (
+	"GoHealthChecker/internal"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxExecOutput is how much of the command output is kept for the log.
+const maxExecOutput = 4 * 1024
+
+// ExecNotifier runs a command for every state change, eg. a script restarting a container.
+// The event is passed in HC_* environment variables and as JSON on stdin.
+// Non-zero exit codes fail the delivery; the exit code and output are logged.
+// Failed commands are not run again unless retries are set, they may have done half of their job.
+type ExecNotifier struct {
+	command   []string
+	timeout   time.Duration
+	retries   int
+	allEvents bool
+}
+
+// NewExecNotifier creates the notifier running command[0] with the arguments command[1:].
+func NewExecNotifier(command []string) *ExecNotifier {
+	return &ExecNotifier{command: command}
+}
+
+// WithTimeout kills the command running longer than timeout, the Dispatcher timeout applies too.
+func (e *ExecNotifier) WithTimeout(timeout time.Duration) *ExecNotifier {
+	e.timeout = timeout
+	return e
+}
+
+// WithRetries runs the failed command again up to retries times, with the backoff of the Dispatcher.
+func (e *ExecNotifier) WithRetries(retries int) *ExecNotifier {
+	e.retries = retries
+	return e
+}
+
+// WithAllEvents runs the command also for flapping, alerts, escalations and groups, not only for state changes.
+func (e *ExecNotifier) WithAllEvents() *ExecNotifier {
+	e.allEvents = true
+	return e
+}
+
+func (e *ExecNotifier) Retries() int {
+	return e.retries
+}
+
+func (e *ExecNotifier) Accepts(event Event) bool {
+	return e.allEvents || (event.Flap == nil && event.Alert == nil && event.Escalation == nil && event.Group == nil)
+}
+
+func (e *ExecNotifier) Name() string {
+	return "exec " + strings.Join(e.command, " ")
+}
+
+func (e *ExecNotifier) Notify(ctx context.Context, event Event) error {
+	if len(e.command) == 0 {
+		return errors.New("no command configured")
+	}
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	stdin, _, err := JSONFormatter{}.Format(event)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Env = append(os.Environ(), eventEnvironment(event)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	output := internal.NewLimitedBuffer(maxExecOutput)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Don't wait forever for children of the command keeping the output open
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+	exitCode := cmd.ProcessState.ExitCode()
+	internal.LOGGER.Info(fmt.Sprintf("Command %s for %s exited with %d after %s, output: %q",
+		e.command[0], event.Target, exitCode, time.Since(start).Round(time.Millisecond), output.String()))
+	if ctx.Err() != nil {
+		return fmt.Errorf("command %s timed out: %w", e.command[0], ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("command %s failed with exit code %d: %w, output: %s", e.command[0], exitCode, err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// eventEnvironment returns the event as HC_* environment variables.
+func eventEnvironment(event Event) []string {
+	variables := map[string]string{
+		"HC_TARGET":      event.Target,
+		"HC_OLD_STATE":   string(event.OldState),
+		"HC_NEW_STATE":   string(event.NewState),
+		"HC_ERROR":       event.Error,
+		"HC_STATUS_CODE": strconv.Itoa(event.StatusCode),
+		"HC_LATENCY_MS":  strconv.FormatInt(event.Latency.Milliseconds(), 10),
+		"HC_TIMESTAMP":   event.Timestamp.UTC().Format(time.RFC3339),
+		"HC_SUMMARY":     event.Summary(),
+		"HC_EVENT":       "state_change",
+	}
+	if event.IsRecovery() {
+		variables["HC_INCIDENT_DURATION_SECONDS"] = strconv.FormatInt(int64(event.IncidentDuration.Seconds()), 10)
+	}
+	if event.Flap != nil {
+		variables["HC_EVENT"] = "flapping"
+	}
+	if event.Escalation != nil {
+		variables["HC_EVENT"] = "reminder"
+		if event.Escalation.Reminder == 0 {
+			variables["HC_EVENT"] = "escalation"
+		}
+		variables["HC_ESCALATION_POLICY"] = event.Escalation.Policy
+	}
+	if event.Group != nil {
+		variables["HC_EVENT"] = "group"
+		targets := make([]string, 0, len(event.Group.Events))
+		for _, member := range event.Group.Events {
+			targets = append(targets, member.Target)
+		}
+		variables["HC_GROUP_TARGETS"] = strings.Join(targets, ",")
+	}
+	if event.Alert != nil {
+		variables["HC_EVENT"] = "alert"
+		variables["HC_ALERT_RULE"] = event.Alert.Rule
+		variables["HC_ALERT_SEVERITY"] = event.Alert.Severity
+		variables["HC_ALERT_STATUS"] = event.alertStatus()
+		variables["HC_ALERT_CONDITION"] = event.Alert.Condition
+	}
+
+	environment := make([]string, 0, len(variables))
+	for name, value := range variables {
+		environment = append(environment, name+"="+value)
+	}
+	return environment
+}
@@ -0,0 +1,281 @@
+package notifier
+
+import (
+	"GoHealthChecker/internal/model"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Formatter renders an Event into the request body expected by a webhook receiver.
+type Formatter interface {
+	Name() string
+	Format(event Event) (body []byte, contentType string, err error)
+}
+
+// NewFormatter returns the formatter with the given name: json (default), slack or teams.
+func NewFormatter(name string) (Formatter, error) {
+	switch name {
+	case "", "json":
+		return JSONFormatter{}, nil
+	case "slack":
+		return SlackFormatter{}, nil
+	case "teams":
+		return TeamsFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported message format: %s", name)
+	}
+}
+
+const (
+	colorDown    = "#D50200"
+	colorUp      = "#2EB886"
+	colorWarning = "#DAA038"
+)
+
+func stateColor(state model.State) string {
+	switch state {
+	case model.StateDown:
+		return colorDown
+	case model.StateUp:
+		return colorUp
+	default:
+		return colorWarning
+	}
+}
+
+// JSONFormatter renders the event as a flat JSON object.
+type JSONFormatter struct{}
+
+type jsonPayload struct {
+	Target                  string          `json:"target"`
+	OldState                model.State     `json:"old_state"`
+	NewState                model.State     `json:"new_state"`
+	Error                   string          `json:"error,omitempty"`
+	StatusCode              int             `json:"status_code"`
+	LatencyMs               int64           `json:"latency_ms"`
+	Timestamp               time.Time       `json:"timestamp"`
+	IncidentDurationSeconds float64         `json:"incident_duration_seconds,omitempty"`
+	Alert                   *jsonAlert      `json:"alert,omitempty"`
+	Escalation              *jsonEscalation `json:"escalation,omitempty"`
+	Group                   *jsonGroup      `json:"group,omitempty"`
+}
+
+type jsonGroup struct {
+	Key      string        `json:"key"`
+	Affected int           `json:"affected"`
+	Targets  []jsonPayload `json:"targets"`
+}
+
+type jsonEscalation struct {
+	Policy         string  `json:"policy"`
+	Reminder       int     `json:"reminder"`
+	Escalated      bool    `json:"escalated"`
+	DownForSeconds float64 `json:"down_for_seconds"`
+}
+
+type jsonAlert struct {
+	Rule      string            `json:"rule"`
+	Severity  string            `json:"severity"`
+	Status    string            `json:"status"`
+	Condition string            `json:"condition"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+func (JSONFormatter) Name() string {
+	return "json"
+}
+
+func (JSONFormatter) Format(event Event) ([]byte, string, error) {
+	payload := jsonPayload{
+		Target:                  event.Target,
+		OldState:                event.OldState,
+		NewState:                event.NewState,
+		Error:                   event.Error,
+		StatusCode:              event.StatusCode,
+		LatencyMs:               event.Latency.Milliseconds(),
+		Timestamp:               event.Timestamp,
+		IncidentDurationSeconds: event.IncidentDuration.Seconds(),
+	}
+	if event.Alert != nil {
+		payload.Alert = &jsonAlert{
+			Rule:      event.Alert.Rule,
+			Severity:  event.Alert.Severity,
+			Status:    strings.ToLower(event.alertStatus()),
+			Condition: event.Alert.Condition,
+			Labels:    event.Alert.Labels,
+		}
+	}
+	if event.Escalation != nil {
+		payload.Escalation = &jsonEscalation{
+			Policy:         event.Escalation.Policy,
+			Reminder:       event.Escalation.Reminder,
+			Escalated:      event.Escalation.Escalated,
+			DownForSeconds: event.Escalation.DownFor.Seconds(),
+		}
+	}
+	if event.Group != nil {
+		payload.Group = &jsonGroup{Key: event.Group.Key, Affected: event.Group.Affected, Targets: make([]jsonPayload, 0, len(event.Group.Events))}
+		for _, member := range event.Group.Events {
+			payload.Group.Targets = append(payload.Group.Targets, jsonPayload{
+				Target:                  member.Target,
+				OldState:                member.OldState,
+				NewState:                member.NewState,
+				Error:                   member.Error,
+				StatusCode:              member.StatusCode,
+				LatencyMs:               member.Latency.Milliseconds(),
+				Timestamp:               member.Timestamp,
+				IncidentDurationSeconds: member.IncidentDuration.Seconds(),
+			})
+		}
+	}
+	body, err := json.Marshal(payload)
+	return body, "application/json", err
+}
+
+// SlackFormatter renders the event for Slack incoming webhooks,
+// as an attachment colored by the new state with Block Kit content.
+type SlackFormatter struct{}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackAttachment struct {
+	Color  string       `json:"color"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"` // Fallback for notifications
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+func (SlackFormatter) Name() string {
+	return "slack"
+}
+
+func (SlackFormatter) Format(event Event) ([]byte, string, error) {
+	fields := []slackText{
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Previous state*\n%s", event.OldState)},
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Latency*\n%s", event.Latency.Round(time.Millisecond))},
+	}
+	if event.StatusCode != 0 {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Status code*\n%d", event.StatusCode)})
+	}
+	if event.IsRecovery() {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Down for*\n%s", event.IncidentDuration.Round(time.Second))})
+	}
+	headline := fmt.Sprintf("*<%s|%s>* is *%s*", event.Target, event.Target, event.NewState)
+	if event.Alert != nil {
+		headline = fmt.Sprintf("*%s* %s for *<%s|%s>*", event.Alert.Rule, event.alertStatus(), event.Target, event.Target)
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Severity*\n%s", event.Alert.Severity)})
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Condition*\n%s", event.Alert.Condition)})
+	}
+	blocks := []slackBlock{
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: headline}},
+		{Type: "section", Fields: fields},
+	}
+	if event.Error != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Last error*\n```%s```", event.Error)}})
+	}
+	blocks = append(blocks, slackBlock{Type: "context", Elements: []slackText{
+		{Type: "mrkdwn", Text: event.Timestamp.UTC().Format(time.RFC3339)},
+	}})
+
+	body, err := json.Marshal(slackMessage{
+		Text:        event.Summary(),
+		Attachments: []slackAttachment{{Color: event.color(), Blocks: blocks}},
+	})
+	return body, "application/json", err
+}
+
+// TeamsFormatter renders the event as a Microsoft Teams MessageCard.
+type TeamsFormatter struct{}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsSection struct {
+	ActivityTitle    string      `json:"activityTitle"`
+	ActivitySubtitle string      `json:"activitySubtitle,omitempty"`
+	Facts            []teamsFact `json:"facts"`
+	Markdown         bool        `json:"markdown"`
+}
+
+type teamsTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+type teamsAction struct {
+	Type    string        `json:"@type"`
+	Name    string        `json:"name"`
+	Targets []teamsTarget `json:"targets"`
+}
+
+type teamsMessageCard struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	ThemeColor      string         `json:"themeColor"`
+	Summary         string         `json:"summary"`
+	Title           string         `json:"title"`
+	Sections        []teamsSection `json:"sections"`
+	PotentialAction []teamsAction  `json:"potentialAction"`
+}
+
+func (TeamsFormatter) Name() string {
+	return "teams"
+}
+
+func (TeamsFormatter) Format(event Event) ([]byte, string, error) {
+	facts := []teamsFact{
+		{Name: "Target", Value: event.Target},
+		{Name: "State", Value: fmt.Sprintf("%s → %s", event.OldState, event.NewState)},
+		{Name: "Latency", Value: event.Latency.Round(time.Millisecond).String()},
+	}
+	if event.StatusCode != 0 {
+		facts = append(facts, teamsFact{Name: "Status code", Value: fmt.Sprintf("%d", event.StatusCode)})
+	}
+	if event.IsRecovery() {
+		facts = append(facts, teamsFact{Name: "Down for", Value: event.IncidentDuration.Round(time.Second).String()})
+	}
+	if event.Error != "" {
+		facts = append(facts, teamsFact{Name: "Last error", Value: event.Error})
+	}
+	if event.Alert != nil {
+		facts = append(facts, teamsFact{Name: "Severity", Value: event.Alert.Severity})
+		facts = append(facts, teamsFact{Name: "Condition", Value: event.Alert.Condition})
+	}
+
+	body, err := json.Marshal(teamsMessageCard{
+		Type:       "MessageCard",
+		Context:    "http://schema.org/extensions",
+		ThemeColor: event.color()[1:], // Teams expects the color without #
+		Summary:    event.Summary(),
+		Title:      event.Title(),
+		Sections: []teamsSection{{
+			ActivityTitle:    event.Summary(),
+			ActivitySubtitle: event.Timestamp.UTC().Format(time.RFC3339),
+			Facts:            facts,
+			Markdown:         true,
+		}},
+		PotentialAction: []teamsAction{{
+			Type:    "OpenUri",
+			Name:    "Open target",
+			Targets: []teamsTarget{{OS: "default", URI: event.Target}},
+		}},
+	})
+	return body, "application/json", err
+}
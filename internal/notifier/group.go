@@ -0,0 +1,262 @@
+package notifier
+
+import (
+	"GoHealthChecker/internal/model"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultGroupWindow is how long a group collects state changes after the first one.
+const DefaultGroupWindow = 30 * time.Second
+
+var httpStatusPattern = regexp.MustCompile(`^HTTP (\d)\d\d$`)
+
+// Group lists the targets aggregated into one event.
+type Group struct {
+	Key      string  // Shared label or error class, eg. "group=payments" or "error=timeout"
+	Events   []Event // State changes of the targets in the event, in order of arrival
+	Affected int     // Number of targets in the group incident so far
+}
+
+// Grouper aggregates state changes of targets sharing a label or the class of their error
+// into one notification, eg. when a failing shared dependency takes down 30 targets at once.
+// The first DOWN event opening the window is sent right away, the DOWN events arriving within
+// the window after it are sent together at its end. Individual recoveries are held back until
+// the whole group is UP again, the recovery of a group of a single target is sent as it is.
+type Grouper struct {
+	window     time.Duration
+	labels     []string
+	errorClass bool
+	targets    map[string]map[string]string // Labels of the targets by URL
+
+	mu       sync.Mutex
+	groups   map[string]*group
+	memberOf map[string]string // Group key of the DOWN targets
+	send     func(event Event)
+}
+
+// group is an open incident of the targets sharing the key.
+type group struct {
+	since     time.Time
+	down      map[string]bool
+	members   map[string]bool // Targets which went DOWN since the group opened
+	pending   []Event         // DOWN events waiting for the end of the window, after the one opening it
+	recovered []Event
+	timer     *time.Timer
+}
+
+// NewGrouper creates the grouper by the label names, with the labels of the targets by their URL.
+func NewGrouper(window time.Duration, labels []string, targets map[string]map[string]string) *Grouper {
+	if window <= 0 {
+		window = DefaultGroupWindow
+	}
+	if targets == nil {
+		targets = make(map[string]map[string]string)
+	}
+	return &Grouper{
+		window:   window,
+		labels:   labels,
+		targets:  targets,
+		groups:   make(map[string]*group),
+		memberOf: make(map[string]string),
+	}
+}
+
+// WithErrorClass groups DOWN targets without a grouping label by the class of their error.
+func (g *Grouper) WithErrorClass() *Grouper {
+	g.errorClass = true
+	return g
+}
+
+// ErrorClass returns the class of the error message, eg. "timeout", "auth" or "HTTP 5xx".
+// Messages of unknown errors are their own class.
+func ErrorClass(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.HasPrefix(lower, "auth failed"):
+		return "auth"
+	case strings.Contains(lower, "timeout") || strings.Contains(lower, "deadline exceeded"):
+		return "timeout"
+	case strings.Contains(lower, "connection refused"):
+		return "connection refused"
+	case strings.Contains(lower, "connection reset"):
+		return "connection reset"
+	case strings.Contains(lower, "no such host") || strings.Contains(lower, "server misbehaving"):
+		return "dns"
+	case strings.Contains(lower, "certificate") || strings.Contains(lower, "tls"):
+		return "tls"
+	}
+	if matches := httpStatusPattern.FindStringSubmatch(message); matches != nil {
+		return "HTTP " + matches[1] + "xx"
+	}
+	return message
+}
+
+// add takes over the event if it belongs to a group, the grouped events are sent later.
+// It returns the events due right away, eg. the DOWN opening the window or the recovery of the whole group.
+func (g *Grouper) add(event Event) (bool, []Event) {
+	if event.Alert != nil || event.Flap != nil || event.Escalation != nil || event.Group != nil {
+		return false, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if key, grouped := g.memberOf[event.Target]; grouped && event.NewState != model.StateDown {
+		delete(g.memberOf, event.Target)
+		grp := g.groups[key]
+		delete(grp.down, event.Target)
+		grp.recovered = append(grp.recovered, event)
+		if len(grp.down) == 0 && (grp.timer == nil || len(grp.members) == 1) {
+			if grp.timer != nil {
+				grp.timer.Stop()
+			}
+			return true, g.ready(key)
+		}
+		return true, nil
+	}
+	if event.NewState != model.StateDown {
+		return false, nil
+	}
+	key := g.key(event)
+	if key == "" {
+		return false, nil
+	}
+	grp, open := g.groups[key]
+	if !open {
+		grp = &group{since: event.Timestamp, down: make(map[string]bool), members: make(map[string]bool)}
+		g.groups[key] = grp
+	}
+	grp.down[event.Target] = true
+	grp.members[event.Target] = true
+	g.memberOf[event.Target] = key
+	// A target failing again within the group is listed once, with its last failure
+	grp.recovered = slices.DeleteFunc(grp.recovered, func(recovered Event) bool { return recovered.Target == event.Target })
+	if grp.timer == nil {
+		// Nothing to wait for, the event opens the window
+		grp.timer = time.AfterFunc(g.window, func() { g.flush(key, grp) })
+		return true, []Event{event}
+	}
+	if i := slices.IndexFunc(grp.pending, func(pending Event) bool { return pending.Target == event.Target }); i >= 0 {
+		grp.pending[i] = event
+	} else {
+		grp.pending = append(grp.pending, event)
+	}
+	return true, nil
+}
+
+func (g *Grouper) key(event Event) string {
+	labels := g.targets[event.Target]
+	for _, name := range g.labels {
+		if value, ok := labels[name]; ok {
+			return name + "=" + value
+		}
+	}
+	if g.errorClass && event.Error != "" {
+		return "error=" + ErrorClass(event.Error)
+	}
+	return ""
+}
+
+// flush sends the events due at the end of the window of grp, unless the group was closed meanwhile.
+func (g *Grouper) flush(key string, grp *group) {
+	g.mu.Lock()
+	var events []Event
+	if g.groups[key] == grp {
+		events = g.ready(key)
+	}
+	send := g.send
+	g.mu.Unlock()
+
+	for _, event := range events {
+		send(event)
+	}
+}
+
+// flushAll returns the events of all groups without waiting for the end of their windows.
+func (g *Grouper) flushAll() []Event {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	keys := make([]string, 0, len(g.groups))
+	for key, grp := range g.groups {
+		if grp.timer != nil {
+			grp.timer.Stop()
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	events := make([]Event, 0)
+	for _, key := range keys {
+		events = append(events, g.ready(key)...)
+	}
+	return events
+}
+
+// ready returns the events of the group which are due and closes the group once all its targets recovered.
+func (g *Grouper) ready(key string) []Event {
+	grp, open := g.groups[key]
+	if !open {
+		return nil
+	}
+	grp.timer = nil
+	events := make([]Event, 0, 2)
+	if len(grp.members) == 1 {
+		// Nothing to aggregate, the target continues as if it wasn't grouped
+		if len(grp.down) == 0 {
+			events = append(events, grp.recovered...)
+			delete(g.groups, key)
+		}
+		return events
+	}
+	if len(grp.pending) > 0 {
+		events = append(events, Event{
+			Target:    key,
+			OldState:  model.StateUp,
+			NewState:  model.StateDown,
+			Error:     sharedError(grp.pending),
+			Timestamp: grp.pending[0].Timestamp,
+			Group:     &Group{Key: key, Events: grp.pending, Affected: len(grp.members)},
+		})
+		grp.pending = nil
+	}
+	if len(grp.down) == 0 {
+		last := grp.recovered[len(grp.recovered)-1]
+		events = append(events, Event{
+			Target:           key,
+			OldState:         model.StateDown,
+			NewState:         model.StateUp,
+			Timestamp:        last.Timestamp,
+			IncidentDuration: last.Timestamp.Sub(grp.since),
+			Group:            &Group{Key: key, Events: grp.recovered, Affected: len(grp.members)},
+		})
+		delete(g.groups, key)
+	}
+	return events
+}
+
+// sharedError returns the error of the events if they all failed the same way.
+func sharedError(events []Event) string {
+	for _, event := range events[1:] {
+		if event.Error != events[0].Error {
+			return ""
+		}
+	}
+	return events[0].Error
+}
+
+// targetList lists the targets of the group with their errors.
+func (g *Group) targetList() string {
+	targets := make([]string, 0, len(g.Events))
+	for _, event := range g.Events {
+		if event.NewState == model.StateDown && event.Error != "" {
+			targets = append(targets, fmt.Sprintf("%s (%s)", event.Target, event.Error))
+		} else {
+			targets = append(targets, event.Target)
+		}
+	}
+	return strings.Join(targets, ", ")
+}
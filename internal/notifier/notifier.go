@@ -0,0 +1,325 @@
+// Package notifier
+//
+// Dispatcher receives events about state changes of the targets and delivers them
+// to all configured notifiers (webhooks, email, ...). Every notifier has its own
+// queue and goroutine, so slow or failing receivers never block the health checks
+// nor each other. Failed deliveries are retried with exponential backoff and every
+// delivery is recorded in a log.
+
+package notifier
+
+import (
+	"GoHealthChecker/internal"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Notifier interface {
+	// Name identifies the notifier in the delivery log.
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+// BatchNotifier is a Notifier which prefers to deliver several events at once,
+// eg. one email about all targets which went down at the same time.
+// The Dispatcher collects events arriving within BatchWindow after the first one.
+type BatchNotifier interface {
+	Notifier
+	BatchWindow() time.Duration
+	NotifyBatch(ctx context.Context, events []Event) error
+}
+
+// RetryPolicy is a Notifier deciding itself how many times failed deliveries are retried,
+// instead of Options.Retries, eg. commands which must not run again after failing.
+type RetryPolicy interface {
+	Retries() int
+}
+
+// EventFilter is a Notifier receiving only some of the events, eg. only the state changes.
+// The state changes aggregated in groups are passed one by one.
+type EventFilter interface {
+	Accepts(event Event) bool
+}
+
+type Options struct {
+	Retries       int           // Additional attempts after a failed delivery
+	RetryBackoff  time.Duration // Delay before the first retry, doubled for every next one
+	Timeout       time.Duration // Timeout of a single delivery attempt
+	QueueSize     int           // Events waiting for delivery per notifier, new events are dropped when full
+	DeliveryLimit int           // Number of deliveries kept in the delivery log
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Retries:       3,
+		RetryBackoff:  time.Second,
+		Timeout:       10 * time.Second,
+		QueueSize:     100,
+		DeliveryLimit: 1000,
+	}
+}
+
+// Delivery is a record of delivering events by one notifier.
+// Events has more than one item only for batch notifiers.
+type Delivery struct {
+	Notifier  string
+	Events    []Event
+	Attempts  int
+	Error     string // Last error, empty if the delivery succeeded
+	Timestamp time.Time
+}
+
+func (d Delivery) Succeeded() bool {
+	return d.Error == ""
+}
+
+func (d Delivery) summary() string {
+	summaries := make([]string, 0, len(d.Events))
+	for _, event := range d.Events {
+		summaries = append(summaries, event.Summary())
+	}
+	return strings.Join(summaries, "; ")
+}
+
+type Dispatcher struct {
+	workers []*worker
+	options Options
+	wg      sync.WaitGroup
+
+	mu         sync.Mutex
+	closed     bool
+	deliveries []Delivery
+	mute       func(event Event) (bool, string)
+	grouper    *Grouper
+}
+
+// worker delivers the events of a single notifier.
+type worker struct {
+	notifier Notifier
+	events   chan Event
+}
+
+func NewDispatcher(notifiers []Notifier, options Options) *Dispatcher {
+	dispatcher := &Dispatcher{
+		workers:    make([]*worker, 0, len(notifiers)),
+		options:    options,
+		deliveries: make([]Delivery, 0),
+	}
+	for _, notifier := range notifiers {
+		w := &worker{
+			notifier: notifier,
+			events:   make(chan Event, max(options.QueueSize, 1)),
+		}
+		dispatcher.workers = append(dispatcher.workers, w)
+		dispatcher.wg.Add(1)
+		go dispatcher.run(w)
+	}
+	return dispatcher
+}
+
+// WithMute sets the function deciding which events are not delivered, eg. about targets
+// under maintenance. It returns whether to mute the event and the reason.
+func (d *Dispatcher) WithMute(mute func(event Event) (bool, string)) *Dispatcher {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mute = mute
+	return d
+}
+
+// WithGrouping aggregates the state changes of targets sharing a label or an error class.
+func (d *Dispatcher) WithGrouping(grouper *Grouper) *Dispatcher {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	grouper.send = d.dispatchGroup
+	d.grouper = grouper
+	return d
+}
+
+// Dispatch queues the event for delivery by every notifier without blocking.
+func (d *Dispatcher) Dispatch(event Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		internal.LOGGER.Warn(fmt.Sprintf("Dispatcher closed, dropping event: %s", event.Summary()))
+		return
+	}
+	if d.mute != nil {
+		if muted, reason := d.mute(event); muted {
+			internal.LOGGER.Info(fmt.Sprintf("Notification muted by %s: %s", reason, event.Summary()))
+			return
+		}
+	}
+	if d.grouper != nil {
+		if grouped, ready := d.grouper.add(event); grouped {
+			for _, groupEvent := range ready {
+				d.enqueue(groupEvent)
+			}
+			return
+		}
+	}
+	d.enqueue(event)
+}
+
+// dispatchGroup queues the event of a group at the end of its window.
+func (d *Dispatcher) dispatchGroup(event Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		internal.LOGGER.Warn(fmt.Sprintf("Dispatcher closed, dropping event: %s", event.Summary()))
+		return
+	}
+	d.enqueue(event)
+}
+
+// enqueue passes the event to the queues of the notifiers, the caller holds the lock.
+func (d *Dispatcher) enqueue(event Event) {
+	for _, w := range d.workers {
+		for _, accepted := range w.accepted(event) {
+			select {
+			case w.events <- accepted:
+			default:
+				internal.LOGGER.Warn(fmt.Sprintf("Notification queue of %s is FULL, dropping event: %s", w.notifier.Name(), accepted.Summary()))
+			}
+		}
+	}
+}
+
+// accepted returns the events the notifier of the worker receives of event.
+func (w *worker) accepted(event Event) []Event {
+	filter, filtering := w.notifier.(EventFilter)
+	if !filtering {
+		return []Event{event}
+	}
+	events := []Event{event}
+	if event.Group != nil {
+		events = event.Group.Events
+	}
+	accepted := make([]Event, 0, len(events))
+	for _, event := range events {
+		if filter.Accepts(event) {
+			accepted = append(accepted, event)
+		}
+	}
+	return accepted
+}
+
+// Deliveries returns a copy of the delivery log, oldest first.
+func (d *Dispatcher) Deliveries() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deliveries := make([]Delivery, len(d.deliveries))
+	copy(deliveries, d.deliveries)
+	return deliveries
+}
+
+// Close stops accepting new events and waits until the queued ones are delivered.
+// Groups waiting for the end of their window are delivered right away.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if !d.closed && d.grouper != nil {
+		for _, event := range d.grouper.flushAll() {
+			d.enqueue(event)
+		}
+	}
+	if !d.closed {
+		d.closed = true
+		for _, w := range d.workers {
+			close(w.events)
+		}
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) run(w *worker) {
+	defer d.wg.Done()
+
+	batcher, batching := w.notifier.(BatchNotifier)
+	for event := range w.events {
+		if !batching {
+			d.record(d.deliver(w.notifier, []Event{event}, func(ctx context.Context) error {
+				return w.notifier.Notify(ctx, event)
+			}))
+			continue
+		}
+		events := collectBatch(event, w.events, batcher.BatchWindow())
+		d.record(d.deliver(w.notifier, events, func(ctx context.Context) error {
+			return batcher.NotifyBatch(ctx, events)
+		}))
+	}
+}
+
+// collectBatch returns first and all events received within window after it.
+func collectBatch(first Event, events <-chan Event, window time.Duration) []Event {
+	batch := []Event{first}
+	if window <= 0 {
+		return batch
+	}
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, event)
+		case <-timer.C:
+			return batch
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(notifier Notifier, events []Event, send func(ctx context.Context) error) Delivery {
+	delivery := Delivery{Notifier: notifier.Name(), Events: events}
+	backoff := d.options.RetryBackoff
+	retries := d.options.Retries
+	if policy, ok := notifier.(RetryPolicy); ok {
+		retries = policy.Retries()
+	}
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		delivery.Attempts++
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if d.options.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(context.Background(), d.options.Timeout)
+		}
+		err := send(ctx)
+		cancel()
+		if err == nil {
+			delivery.Error = ""
+			break
+		}
+		delivery.Error = err.Error()
+		internal.LOGGER.Warn(fmt.Sprintf("Delivery of %q by %s failed (attempt %d): %s",
+			delivery.summary(), notifier.Name(), delivery.Attempts, err))
+	}
+	delivery.Timestamp = time.Now().UTC()
+	return delivery
+}
+
+func (d *Dispatcher) record(delivery Delivery) {
+	if delivery.Succeeded() {
+		internal.LOGGER.Info(fmt.Sprintf("Delivered %q by %s", delivery.summary(), delivery.Notifier))
+	} else {
+		internal.LOGGER.Error(fmt.Sprintf("Giving up delivery of %q by %s after %d attempts: %s",
+			delivery.summary(), delivery.Notifier, delivery.Attempts, delivery.Error))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deliveries = append(d.deliveries, delivery)
+	if limit := d.options.DeliveryLimit; limit > 0 && len(d.deliveries) > limit {
+		d.deliveries = d.deliveries[len(d.deliveries)-limit:]
+	}
+}
@@ -0,0 +1,212 @@
+package notifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	DefaultOpsgenieURL  = "https://api.opsgenie.com"
+
+	pagingSource = "GoHealthChecker"
+)
+
+// DedupKey is the stable incident key of a target, so that a recovery resolves
+// the incident opened when the target went down.
+func DedupKey(target string) string {
+	sum := sha256.Sum256([]byte(target))
+	return "gohealthchecker-" + hex.EncodeToString(sum[:16])
+}
+
+var pagerDutySeverities = map[string]bool{"critical": true, "error": true, "warning": true, "info": true}
+
+// PagerDutyNotifier sends trigger/resolve events to the PagerDuty Events API v2.
+type PagerDutyNotifier struct {
+	url        string
+	routingKey string
+	severity   string
+	client     *http.Client
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     time.Time         `json:"timestamp"`
+	Component     string            `json:"component,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+// NewPagerDutyNotifier creates the notifier, empty endpoint defaults to DefaultPagerDutyURL
+// and empty severity to "critical".
+func NewPagerDutyNotifier(endpoint string, routingKey string, severity string) *PagerDutyNotifier {
+	if endpoint == "" {
+		endpoint = DefaultPagerDutyURL
+	}
+	if severity == "" {
+		severity = "critical"
+	}
+	return &PagerDutyNotifier{
+		url:        endpoint,
+		routingKey: routingKey,
+		severity:   severity,
+		client:     &http.Client{},
+	}
+}
+
+func (p *PagerDutyNotifier) Name() string {
+	return "pagerduty " + p.url
+}
+
+func (p *PagerDutyNotifier) Notify(ctx context.Context, event Event) error {
+	if !event.Pages() {
+		return nil
+	}
+	request := pagerDutyEvent{
+		RoutingKey: p.routingKey,
+		DedupKey:   event.DedupKey(),
+	}
+	if event.Resolved() {
+		request.EventAction = "resolve"
+	} else {
+		severity := p.severity
+		if event.Alert != nil && pagerDutySeverities[event.Alert.Severity] {
+			severity = event.Alert.Severity
+		}
+		request.EventAction = "trigger"
+		request.Payload = &pagerDutyPayload{
+			Summary:       event.Summary(),
+			Source:        event.Target,
+			Severity:      severity,
+			Timestamp:     event.Timestamp,
+			Component:     pagingSource,
+			CustomDetails: eventDetails(event),
+		}
+		request.Links = []pagerDutyLink{{Href: event.Target, Text: "Target"}}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	return post(ctx, p.client, p.url, nil, body, "application/json")
+}
+
+// OpsgenieNotifier creates Opsgenie alerts when a target goes down and closes them on recovery.
+type OpsgenieNotifier struct {
+	baseURL  string
+	apiKey   string
+	priority string
+	client   *http.Client
+}
+
+type opsgenieAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// NewOpsgenieNotifier creates the notifier, empty baseURL defaults to DefaultOpsgenieURL
+// and empty priority to "P1".
+func NewOpsgenieNotifier(baseURL string, apiKey string, priority string) *OpsgenieNotifier {
+	if baseURL == "" {
+		baseURL = DefaultOpsgenieURL
+	}
+	if priority == "" {
+		priority = "P1"
+	}
+	return &OpsgenieNotifier{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		priority: priority,
+		client:   &http.Client{},
+	}
+}
+
+func (o *OpsgenieNotifier) Name() string {
+	return "opsgenie " + o.baseURL
+}
+
+func (o *OpsgenieNotifier) Notify(ctx context.Context, event Event) error {
+	if !event.Pages() {
+		return nil
+	}
+	headers := map[string]string{"Authorization": "GenieKey " + o.apiKey}
+	alias := event.DedupKey()
+
+	if event.Resolved() {
+		body, err := json.Marshal(opsgenieClose{Source: pagingSource, Note: event.Summary()})
+		if err != nil {
+			return err
+		}
+		endpoint := o.baseURL + "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+		return post(ctx, o.client, endpoint, headers, body, "application/json")
+	}
+
+	message := event.Summary()
+	// Opsgenie truncates messages longer than 130 characters
+	if runes := []rune(message); len(runes) > 130 {
+		message = string(runes[:127]) + "..."
+	}
+	body, err := json.Marshal(opsgenieAlert{
+		Message:     message,
+		Alias:       alias,
+		Description: event.Summary(),
+		Source:      pagingSource,
+		Priority:    o.priority,
+		Details:     eventDetails(event),
+	})
+	if err != nil {
+		return err
+	}
+	return post(ctx, o.client, o.baseURL+"/v2/alerts", headers, body, "application/json")
+}
+
+func eventDetails(event Event) map[string]string {
+	details := map[string]string{
+		"target":    event.Target,
+		"old_state": string(event.OldState),
+		"new_state": string(event.NewState),
+		"latency":   event.Latency.Round(time.Millisecond).String(),
+	}
+	if event.Error != "" {
+		details["error"] = event.Error
+	}
+	if event.Alert != nil {
+		details["rule"] = event.Alert.Rule
+		details["severity"] = event.Alert.Severity
+		details["condition"] = event.Alert.Condition
+		for name, value := range event.Alert.Labels {
+			details["label_"+name] = value
+		}
+	}
+	return details
+}
@@ -0,0 +1,268 @@
+package notifier
+
+import (
+	"GoHealthChecker/internal/model"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLS modes of the SMTP connection.
+const (
+	SMTPTLSStartTLS = "starttls" // Plain connection upgraded with STARTTLS, required to succeed
+	SMTPTLSImplicit = "implicit" // TLS from the first byte, usually port 465
+	SMTPTLSNone     = "none"     // No encryption, only for local relays
+)
+
+// DefaultSMTPBatchWindow batches the targets failing at once, eg. after a network outage, into one email.
+const DefaultSMTPBatchWindow = 10 * time.Second
+
+type SMTPSettings struct {
+	Host               string
+	Port               int
+	Username           string // Optional, enables AUTH PLAIN
+	Password           string
+	From               string
+	To                 []string
+	TLS                string // starttls (default), implicit or none
+	InsecureSkipVerify bool
+	// BatchWindow collects state changes arriving shortly after each other into one email,
+	// DefaultSMTPBatchWindow when zero, a negative window sends every state change right away
+	BatchWindow time.Duration
+}
+
+// SMTPNotifier emails DOWN and RECOVERED notifications.
+type SMTPNotifier struct {
+	settings  SMTPSettings
+	tlsConfig *tls.Config
+}
+
+func NewSMTPNotifier(settings SMTPSettings) *SMTPNotifier {
+	if settings.TLS == "" {
+		settings.TLS = SMTPTLSStartTLS
+	}
+	if settings.BatchWindow == 0 {
+		settings.BatchWindow = DefaultSMTPBatchWindow
+	}
+	return &SMTPNotifier{
+		settings: settings,
+		tlsConfig: &tls.Config{
+			ServerName:         settings.Host,
+			InsecureSkipVerify: settings.InsecureSkipVerify,
+		},
+	}
+}
+
+// WithTLSConfig replaces the TLS configuration, eg. to trust a private CA.
+func (s *SMTPNotifier) WithTLSConfig(config *tls.Config) *SMTPNotifier {
+	s.tlsConfig = config
+	return s
+}
+
+func (s *SMTPNotifier) Name() string {
+	return fmt.Sprintf("smtp %s:%d", s.settings.Host, s.settings.Port)
+}
+
+func (s *SMTPNotifier) BatchWindow() time.Duration {
+	return s.settings.BatchWindow
+}
+
+func (s *SMTPNotifier) Notify(ctx context.Context, event Event) error {
+	return s.NotifyBatch(ctx, []Event{event})
+}
+
+func (s *SMTPNotifier) NotifyBatch(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return s.send(ctx, composeEmail(s.settings.From, s.settings.To, events))
+}
+
+func (s *SMTPNotifier) send(ctx context.Context, message []byte) error {
+	address := net.JoinHostPort(s.settings.Host, strconv.Itoa(s.settings.Port))
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if s.settings.TLS == SMTPTLSImplicit {
+		tlsConn := tls.Client(conn, s.tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return err
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, s.settings.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() { _ = client.Close() }()
+
+	if s.settings.TLS == SMTPTLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(s.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.settings.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.settings.Username, s.settings.Password, s.settings.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.settings.From); err != nil {
+		return err
+	}
+	for _, recipient := range s.settings.To {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", recipient, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// composeEmail renders events into a plain text email, one section per event.
+func composeEmail(from string, to []string, events []Event) []byte {
+	var body bytes.Buffer
+	for i, event := range events {
+		if i > 0 {
+			body.WriteString("\r\n" + strings.Repeat("-", 60) + "\r\n\r\n")
+		}
+		writeEventSection(&body, event)
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", emailSubject(events))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes()
+}
+
+func emailSubject(events []Event) string {
+	if len(events) == 1 && events[0].Alert != nil {
+		return fmt.Sprintf("[%s] %s %s", emailStatus(events[0]), events[0].Alert.Rule, events[0].Target)
+	}
+	if len(events) == 1 {
+		return fmt.Sprintf("[%s] %s", emailStatus(events[0]), events[0].Target)
+	}
+
+	down := make([]string, 0, len(events))
+	recovered := make([]string, 0, len(events))
+	for _, event := range events {
+		if event.Resolved() && event.Pages() {
+			recovered = append(recovered, event.Target)
+		} else {
+			down = append(down, event.Target)
+		}
+	}
+	switch {
+	case len(recovered) == 0:
+		return fmt.Sprintf("[DOWN] %d targets: %s", len(down), strings.Join(down, ", "))
+	case len(down) == 0:
+		return fmt.Sprintf("[RECOVERED] %d targets: %s", len(recovered), strings.Join(recovered, ", "))
+	default:
+		return fmt.Sprintf("[DOWN] %d targets, [RECOVERED] %d targets", len(down), len(recovered))
+	}
+}
+
+func emailStatus(event Event) string {
+	if event.Alert != nil {
+		return event.alertStatus()
+	}
+	if event.Flap != nil && !event.Flap.Flapping {
+		return "STABLE"
+	}
+	if event.IsRecovery() {
+		return "RECOVERED"
+	}
+	return string(event.NewState)
+}
+
+func writeEventSection(body *bytes.Buffer, event Event) {
+	fmt.Fprintf(body, "%s\r\n\r\n", event.Summary())
+	fmt.Fprintf(body, "Target:      %s\r\n", event.Target)
+	fmt.Fprintf(body, "State:       %s -> %s\r\n", event.OldState, event.NewState)
+	if event.Alert != nil {
+		fmt.Fprintf(body, "Rule:        %s (%s)\r\n", event.Alert.Rule, event.Alert.Severity)
+		fmt.Fprintf(body, "Condition:   %s\r\n", event.Alert.Condition)
+	}
+	fmt.Fprintf(body, "Time:        %s\r\n", event.Timestamp.UTC().Format(time.RFC3339))
+	if event.StatusCode != 0 {
+		fmt.Fprintf(body, "Status code: %d\r\n", event.StatusCode)
+	}
+	fmt.Fprintf(body, "Latency:     %s\r\n", event.Latency.Round(time.Millisecond))
+	if event.Error != "" {
+		fmt.Fprintf(body, "Last error:  %s\r\n", event.Error)
+	}
+	if event.IsRecovery() {
+		fmt.Fprintf(body, "Down for:    %s\r\n", event.IncidentDuration.Round(time.Second))
+	}
+
+	if len(event.History) == 0 {
+		return
+	}
+	body.WriteString("\r\nRecent checks:\r\n")
+	for _, result := range event.History {
+		fmt.Fprintf(body, "  %s  %-4s  %s  %s\r\n",
+			result.Timestamp.UTC().Format(time.RFC3339), historyState(result),
+			result.Latency.Round(time.Millisecond), historyOutcome(event.Target, result))
+	}
+}
+
+func historyState(result model.HealthCheckResult) string {
+	if result.IsOk {
+		return "OK"
+	}
+	return "FAIL"
+}
+
+// historyOutcome describes a result of target: the status code of HTTP checks, the output of exec checks
+// or just OK for the protocols without status codes, eg. TCP.
+func historyOutcome(target string, result model.HealthCheckResult) string {
+	switch {
+	case !result.IsOk:
+		return result.ErrorMessage()
+	case result.Output != "":
+		return result.Output
+	case httpTarget(target):
+		return fmt.Sprintf("HTTP %d", result.StatusCode)
+	default:
+		return "OK"
+	}
+}
+
+// httpTarget reports whether target is checked over HTTP, the only checks with status codes.
+func httpTarget(target string) bool {
+	scheme, _, _ := strings.Cut(strings.ToLower(target), "://")
+	return scheme == "http" || scheme == "https" || scheme == "unix"
+}
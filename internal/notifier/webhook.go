@@ -0,0 +1,72 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookNotifier POSTs every event to a configured URL, rendered by its Formatter (JSON by default).
+type WebhookNotifier struct {
+	url       string
+	headers   map[string]string
+	client    *http.Client
+	formatter Formatter
+}
+
+func NewWebhookNotifier(url string, headers map[string]string) *WebhookNotifier {
+	return NewWebhookNotifierWithClient(url, headers, &http.Client{})
+}
+
+func NewWebhookNotifierWithClient(url string, headers map[string]string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:       url,
+		headers:   headers,
+		client:    client,
+		formatter: JSONFormatter{},
+	}
+}
+
+func (w *WebhookNotifier) WithFormatter(formatter Formatter) *WebhookNotifier {
+	w.formatter = formatter
+	return w
+}
+
+func (w *WebhookNotifier) Name() string {
+	return fmt.Sprintf("webhook(%s) %s", w.formatter.Name(), w.url)
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, contentType, err := w.formatter.Format(event)
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.client, w.url, w.headers, body, contentType)
+}
+
+// post sends body to url and fails on non 2xx responses.
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded with HTTP %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+// Package rules
+//
+// Engine periodically evaluates alert rules against the results kept in the Store.
+// A rule whose expression holds for a target becomes pending, and firing once it
+// held for the whole For duration. Firing and resolved alerts are sent to the
+// notifier Dispatcher like state changes of the targets.
+
+package rules
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/internal/store"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const DefaultEvaluationInterval = 30 * time.Second
+
+// Severities accepted by rules, from the most to the least urgent.
+var Severities = []string{"critical", "error", "warning", "info"}
+
+type Rule struct {
+	Name       string
+	Expression *Expression
+	Severity   string
+	For        time.Duration     // How long the expression must hold before the alert fires
+	Labels     map[string]string // Attached to the notifications
+	Targets    []string          // URLs the rule applies to, all targets when empty
+}
+
+// NewRule parses the expression and creates the rule, empty severity defaults to "warning".
+func NewRule(name string, expression string, severity string, forDuration time.Duration) (*Rule, error) {
+	if name == "" {
+		return nil, fmt.Errorf("rule has no name")
+	}
+	if severity == "" {
+		severity = "warning"
+	}
+	if !slices.Contains(Severities, severity) {
+		return nil, fmt.Errorf("rule %s: unsupported severity %s", name, severity)
+	}
+	if forDuration < 0 {
+		return nil, fmt.Errorf("rule %s: for must not be negative", name)
+	}
+	parsed, err := ParseExpression(expression)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: %w", name, err)
+	}
+	return &Rule{
+		Name:       name,
+		Expression: parsed,
+		Severity:   severity,
+		For:        forDuration,
+		Labels:     make(map[string]string),
+	}, nil
+}
+
+func (r *Rule) WithLabels(labels map[string]string) *Rule {
+	for name, value := range labels {
+		r.Labels[name] = value
+	}
+	return r
+}
+
+func (r *Rule) WithTargets(targets []string) *Rule {
+	r.Targets = targets
+	return r
+}
+
+func (r *Rule) appliesTo(url string) bool {
+	return len(r.Targets) == 0 || slices.Contains(r.Targets, url)
+}
+
+type AlertState string
+
+const (
+	AlertPending AlertState = "PENDING"
+	AlertFiring  AlertState = "FIRING"
+)
+
+// Alert is an active (pending or firing) alert of a rule for one target.
+type Alert struct {
+	Rule      string
+	Target    string
+	Severity  string
+	State     AlertState
+	Condition string    // Last evaluated expression with its value
+	Since     time.Time // When the expression started to hold
+}
+
+type Engine struct {
+	store           store.Store
+	dispatcher      *notifier.Dispatcher // Optional, receives firing and resolved alerts
+	rules           []*Rule
+	interval        time.Duration
+	pollingInterval time.Duration
+
+	mu     sync.Mutex
+	alerts map[string]*Alert // Keyed by rule and target
+}
+
+// NewEngine creates the engine evaluating rules every interval. pollingInterval of the
+// health checks sizes the history read for count based functions like failures().
+func NewEngine(resultStore store.Store, rules []*Rule, interval time.Duration, pollingInterval time.Duration) *Engine {
+	if interval <= 0 {
+		interval = DefaultEvaluationInterval
+	}
+	return &Engine{
+		store:           resultStore,
+		rules:           rules,
+		interval:        interval,
+		pollingInterval: pollingInterval,
+		alerts:          make(map[string]*Alert),
+	}
+}
+
+func (e *Engine) WithDispatcher(dispatcher *notifier.Dispatcher) *Engine {
+	e.dispatcher = dispatcher
+	return e
+}
+
+// Run evaluates the rules every interval until ctx is done.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.Evaluate(now)
+		}
+	}
+}
+
+// Evaluate checks all rules against all targets at the time now and returns the
+// notifications sent for alerts which started firing or were resolved.
+func (e *Engine) Evaluate(now time.Time) []notifier.Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	events := make([]notifier.Event, 0)
+	latest := e.store.GetLatestResults()
+	for _, rule := range e.rules {
+		for _, url := range e.store.GetURLs() {
+			if !rule.appliesTo(url) {
+				continue
+			}
+			data := Data{Now: now, Latest: latest[url]}
+			if lookback := rule.Expression.Lookback(e.pollingInterval); lookback > 0 {
+				data.History = e.store.GetHistory(url, now.Add(-lookback), now)
+			}
+			value, matches, ok := rule.Expression.Evaluate(data)
+			if !ok {
+				// Not enough data yet, keep the alert as it is
+				continue
+			}
+			if event := e.update(rule, url, data, rule.Expression.Describe(value), matches); event != nil {
+				events = append(events, *event)
+			}
+		}
+	}
+
+	for _, event := range events {
+		internal.LOGGER.Info(fmt.Sprintf("Alert %s", event.Summary()))
+		if e.dispatcher != nil {
+			e.dispatcher.Dispatch(event)
+		}
+	}
+	return events
+}
+
+// Alerts returns the active alerts, both pending and firing.
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		alerts = append(alerts, *alert)
+	}
+	slices.SortFunc(alerts, func(a, b Alert) int {
+		if a.Rule != b.Rule {
+			return cmp.Compare(a.Rule, b.Rule)
+		}
+		return cmp.Compare(a.Target, b.Target)
+	})
+	return alerts
+}
+
+// update moves the alert of rule for the target of data and returns the event to send, if any.
+func (e *Engine) update(rule *Rule, url string, data Data, condition string, matches bool) *notifier.Event {
+	key := rule.Name + "\x00" + url
+	alert, active := e.alerts[key]
+
+	if !matches {
+		if !active {
+			return nil
+		}
+		delete(e.alerts, key)
+		if alert.State != AlertFiring {
+			return nil
+		}
+		event := e.newEvent(rule, url, data, condition, false)
+		return &event
+	}
+
+	if !active {
+		alert = &Alert{Rule: rule.Name, Target: url, Severity: rule.Severity, State: AlertPending, Since: data.Now}
+		e.alerts[key] = alert
+	}
+	alert.Condition = condition
+	if alert.State == AlertPending && data.Now.Sub(alert.Since) >= rule.For {
+		alert.State = AlertFiring
+		event := e.newEvent(rule, url, data, condition, true)
+		return &event
+	}
+	return nil
+}
+
+func (e *Engine) newEvent(rule *Rule, url string, data Data, condition string, firing bool) notifier.Event {
+	result := data.Latest
+	event := notifier.Event{
+		Target:     url,
+		OldState:   result.State,
+		NewState:   result.State,
+		StatusCode: result.StatusCode,
+		Latency:    result.Latency,
+		Timestamp:  data.Now,
+		Alert: &notifier.Alert{
+			Rule:      rule.Name,
+			Severity:  rule.Severity,
+			Labels:    rule.Labels,
+			Condition: condition,
+			Firing:    firing,
+		},
+	}
+	if !result.IsOk {
+		event.Error = result.ErrorMessage()
+	}
+	history := data.History
+	if len(history) == 0 {
+		history = e.store.GetHistory(url, data.Now.Add(-notifier.HistorySize*e.pollingInterval), data.Now)
+	}
+	event.History = history[max(len(history)-notifier.HistorySize, 0):]
+	return event
+}
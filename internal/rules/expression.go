@@ -0,0 +1,330 @@
+package rules
+
+import (
+	"GoHealthChecker/internal/model"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expression is a parsed rule condition: <function>[(<args>)] <comparator> <threshold>[unit], eg.
+//
+//	p95(latency, 5m) > 800ms
+//	uptime(1h) < 99%
+//	cert_expiry < 14d
+//	failures(5) >= 3
+type Expression struct {
+	Source     string
+	Function   string
+	Field      string        // latency or size, for aggregations
+	Window     time.Duration // Time window of the aggregation
+	Count      int           // Number of last checks, for failures()
+	Comparator string
+	Threshold  float64 // In the unit of the function: milliseconds, percent, bytes or a plain number
+}
+
+type unit int
+
+const (
+	unitNumber unit = iota
+	unitDuration
+	unitPercent
+	unitBytes
+)
+
+type argument int
+
+const (
+	argField argument = iota
+	argWindow
+	argCount
+)
+
+type function struct {
+	args []argument
+	unit unit // unit of the value, unitDuration/unitBytes for aggregations follow the field
+	eval func(expression *Expression, data Data) (float64, bool)
+}
+
+var expressionRegex = regexp.MustCompile(`^\s*([a-z_][a-z0-9_]*)\s*(?:\(([^)]*)\))?\s*(>=|<=|==|!=|>|<)\s*([0-9]+(?:\.[0-9]+)?)\s*(ms|s|m|h|d|%|B|KB|MB)?\s*$`)
+
+var functions = map[string]function{
+	"p50":         {args: []argument{argField, argWindow}, eval: aggregate(func(v []float64) float64 { return model.Percentile(v, 50) })},
+	"p90":         {args: []argument{argField, argWindow}, eval: aggregate(func(v []float64) float64 { return model.Percentile(v, 90) })},
+	"p95":         {args: []argument{argField, argWindow}, eval: aggregate(func(v []float64) float64 { return model.Percentile(v, 95) })},
+	"p99":         {args: []argument{argField, argWindow}, eval: aggregate(func(v []float64) float64 { return model.Percentile(v, 99) })},
+	"avg":         {args: []argument{argField, argWindow}, eval: aggregate(average)},
+	"min":         {args: []argument{argField, argWindow}, eval: aggregate(func(v []float64) float64 { return v[0] })},
+	"max":         {args: []argument{argField, argWindow}, eval: aggregate(func(v []float64) float64 { return v[len(v)-1] })},
+	"uptime":      {args: []argument{argWindow}, unit: unitPercent, eval: uptime},
+	"failures":    {args: []argument{argCount}, unit: unitNumber, eval: failures},
+	"cert_expiry": {unit: unitDuration, eval: certExpiry},
+	"latency":     {unit: unitDuration, eval: lastLatency},
+	"status_code": {unit: unitNumber, eval: lastStatusCode},
+}
+
+// Data is what an expression is evaluated against for one target.
+type Data struct {
+	Now     time.Time
+	Latest  model.HealthCheckResult
+	History []model.HealthCheckResult // Oldest first, covering at least the window of the expression
+}
+
+func ParseExpression(source string) (*Expression, error) {
+	match := expressionRegex.FindStringSubmatch(source)
+	if match == nil {
+		return nil, fmt.Errorf("invalid expression %q, expected eg. \"p95(latency, 5m) > 800ms\"", source)
+	}
+	expression := &Expression{
+		Source:     strings.TrimSpace(source),
+		Function:   match[1],
+		Comparator: match[3],
+	}
+	fn, exists := functions[expression.Function]
+	if !exists {
+		return nil, fmt.Errorf("unknown function %q in %q", expression.Function, source)
+	}
+
+	args := make([]string, 0)
+	if strings.TrimSpace(match[2]) != "" {
+		for _, arg := range strings.Split(match[2], ",") {
+			args = append(args, strings.TrimSpace(arg))
+		}
+	}
+	if len(args) != len(fn.args) {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", expression.Function, len(fn.args), len(args))
+	}
+	for i, kind := range fn.args {
+		if err := expression.parseArgument(kind, args[i]); err != nil {
+			return nil, fmt.Errorf("%s: %w", expression.Function, err)
+		}
+	}
+
+	threshold, err := strconv.ParseFloat(match[4], 64)
+	if err != nil {
+		return nil, err
+	}
+	expression.Threshold, err = convertThreshold(threshold, match[5], expression.unit())
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", source, err)
+	}
+	return expression, nil
+}
+
+// Evaluate returns the value of the function and whether the condition holds.
+// ok is false when there is not enough data to evaluate the function.
+func (e *Expression) Evaluate(data Data) (value float64, matches bool, ok bool) {
+	value, ok = functions[e.Function].eval(e, data)
+	if !ok {
+		return 0, false, false
+	}
+	switch e.Comparator {
+	case ">":
+		matches = value > e.Threshold
+	case ">=":
+		matches = value >= e.Threshold
+	case "<":
+		matches = value < e.Threshold
+	case "<=":
+		matches = value <= e.Threshold
+	case "==":
+		matches = value == e.Threshold
+	case "!=":
+		matches = value != e.Threshold
+	}
+	return value, matches, true
+}
+
+// Describe renders the evaluated value with the condition, eg. "p95(latency, 5m) = 950ms > 800ms".
+func (e *Expression) Describe(value float64) string {
+	left := e.Source[:strings.IndexAny(e.Source, "<>=!")]
+	return fmt.Sprintf("%s = %s %s %s", strings.TrimSpace(left), e.format(value), e.Comparator, e.format(e.Threshold))
+}
+
+// Lookback is how much history the expression needs, zero if it only needs the latest result.
+// Count based functions are covered by twice their number of polling intervals.
+func (e *Expression) Lookback(pollingInterval time.Duration) time.Duration {
+	if e.Count > 0 {
+		return 2 * time.Duration(e.Count) * pollingInterval
+	}
+	return e.Window
+}
+
+func (e *Expression) unit() unit {
+	fn := functions[e.Function]
+	if len(fn.args) > 0 && fn.args[0] == argField {
+		if e.Field == "size" {
+			return unitBytes
+		}
+		return unitDuration
+	}
+	return fn.unit
+}
+
+func (e *Expression) format(value float64) string {
+	switch e.unit() {
+	case unitDuration:
+		return time.Duration(value * float64(time.Millisecond)).Round(time.Millisecond).String()
+	case unitPercent:
+		return strconv.FormatFloat(value, 'f', 2, 64) + "%"
+	case unitBytes:
+		return strconv.FormatFloat(value, 'f', 0, 64) + "B"
+	default:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+}
+
+func (e *Expression) parseArgument(kind argument, arg string) error {
+	switch kind {
+	case argField:
+		if arg != "latency" && arg != "size" {
+			return fmt.Errorf("unknown field %q, expected latency or size", arg)
+		}
+		e.Field = arg
+	case argWindow:
+		window, err := ParseDuration(arg)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("invalid window %q", arg)
+		}
+		e.Window = window
+	case argCount:
+		count, err := strconv.Atoi(arg)
+		if err != nil || count <= 0 {
+			return fmt.Errorf("invalid count %q", arg)
+		}
+		e.Count = count
+	}
+	return nil
+}
+
+// ParseDuration extends time.ParseDuration with days, eg. "14d".
+func ParseDuration(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		count, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(count * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(value)
+}
+
+func convertThreshold(value float64, suffix string, valueUnit unit) (float64, error) {
+	if suffix == "" {
+		return value, nil
+	}
+	switch valueUnit {
+	case unitDuration:
+		multipliers := map[string]time.Duration{"ms": time.Millisecond, "s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+		if multiplier, ok := multipliers[suffix]; ok {
+			return value * float64(multiplier) / float64(time.Millisecond), nil
+		}
+	case unitPercent:
+		if suffix == "%" {
+			return value, nil
+		}
+	case unitBytes:
+		multipliers := map[string]float64{"B": 1, "KB": 1024, "MB": 1024 * 1024}
+		if multiplier, ok := multipliers[suffix]; ok {
+			return value * multiplier, nil
+		}
+	}
+	return 0, fmt.Errorf("unit %q doesn't match the function", suffix)
+}
+
+func aggregate(reduce func(sorted []float64) float64) func(*Expression, Data) (float64, bool) {
+	return func(expression *Expression, data Data) (float64, bool) {
+		values := make([]float64, 0, len(data.History))
+		for _, result := range inWindow(data, expression.Window) {
+			if expression.Field == "size" {
+				values = append(values, float64(result.Size))
+			} else {
+				values = append(values, float64(result.Latency.Milliseconds()))
+			}
+		}
+		if len(values) == 0 {
+			return 0, false
+		}
+		sort.Float64s(values)
+		return reduce(values), true
+	}
+}
+
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func uptime(expression *Expression, data Data) (float64, bool) {
+	// Checks during maintenance and inconclusive checks don't count
+	total, successes := 0, 0
+	for _, result := range inWindow(data, expression.Window) {
+		if result.Maintenance || result.Inconclusive() {
+			continue
+		}
+		total++
+		if result.IsOk {
+			successes++
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(successes) / float64(total) * 100, true
+}
+
+func failures(expression *Expression, data Data) (float64, bool) {
+	counted := make([]model.HealthCheckResult, 0, len(data.History))
+	for _, result := range data.History {
+		if !result.Maintenance && !result.Inconclusive() {
+			counted = append(counted, result)
+		}
+	}
+	if len(counted) == 0 {
+		return 0, false
+	}
+	count := 0
+	for _, result := range counted[max(len(counted)-expression.Count, 0):] {
+		if !result.IsOk {
+			count++
+		}
+	}
+	return float64(count), true
+}
+
+func certExpiry(_ *Expression, data Data) (float64, bool) {
+	if data.Latest.CertExpiry.IsZero() {
+		return 0, false
+	}
+	return float64(data.Latest.CertExpiry.Sub(data.Now).Milliseconds()), true
+}
+
+func lastLatency(_ *Expression, data Data) (float64, bool) {
+	if data.Latest.Timestamp.IsZero() {
+		return 0, false
+	}
+	return float64(data.Latest.Latency.Milliseconds()), true
+}
+
+func lastStatusCode(_ *Expression, data Data) (float64, bool) {
+	if data.Latest.Timestamp.IsZero() {
+		return 0, false
+	}
+	return float64(data.Latest.StatusCode), true
+}
+
+func inWindow(data Data, window time.Duration) []model.HealthCheckResult {
+	since := data.Now.Add(-window)
+	for i, result := range data.History {
+		if !result.Timestamp.Before(since) {
+			return data.History[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	urllib "net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Kinds of failures of the data store checks, eg. to tell rejected credentials from a server which is down.
+const (
+	FailureUnreachable = "unreachable" // Connecting failed or timed out
+	FailureAuth        = "auth"        // The credentials were rejected
+	FailureProtocol    = "protocol"    // Unexpected response, eg. another server listens on the port
+	FailureQuery       = "query"       // Connected, but the health query failed
+)
+
+var failureMessages = map[string]string{
+	FailureUnreachable: "unreachable",
+	FailureAuth:        "auth failed",
+	FailureProtocol:    "protocol error",
+	FailureQuery:       "query failed",
+}
+
+// CheckError is a failed check of a data store classified by the kind of the failure.
+type CheckError struct {
+	Kind string
+	Err  error
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("%s: %s", failureMessages[e.Kind], e.Err)
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+func failure(kind string, format string, args ...any) *CheckError {
+	return &CheckError{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// FailureKind returns the kind of the failure of a data store check, empty for other errors.
+func FailureKind(err error) string {
+	var checkError *CheckError
+	if errors.As(err, &checkError) {
+		return checkError.Kind
+	}
+	return ""
+}
+
+// credentials returns the user and password of the target. The password is taken from the environment
+// variable named by the password_env query parameter or the file password_file, never from the URL.
+func credentials(target *urllib.URL) (string, string, error) {
+	user := target.User.Username()
+	password := ""
+	query := target.Query()
+	if name := query.Get("password_env"); name != "" {
+		password = os.Getenv(name)
+	}
+	if path := query.Get("password_file"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read the password: %w", err)
+		}
+		password = strings.TrimRight(string(content), "\r\n")
+	}
+	return user, password, nil
+}
+
+// dialDataStore connects to the target, on defaultPort if the URL has none.
+// The whole check must finish within timeout.
+func dialDataStore(dialer *net.Dialer, target *urllib.URL, defaultPort string, timeout time.Duration) (net.Conn, error) {
+	address := target.Host
+	if target.Port() == "" {
+		address = net.JoinHostPort(target.Hostname(), defaultPort)
+	}
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return nil, &CheckError{Kind: FailureUnreachable, Err: err}
+	}
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+	}
+	return conn, nil
+}
+
+// ioFailure classifies errors of reading and writing the connection, eg. the server closing it.
+func ioFailure(err error) *CheckError {
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return &CheckError{Kind: FailureUnreachable, Err: err}
+	}
+	return &CheckError{Kind: FailureProtocol, Err: err}
+}
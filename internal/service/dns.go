@@ -0,0 +1,194 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/dns"
+	"GoHealthChecker/internal/model"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	urllib "net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNSService resolves a name against a resolver, eg. dns://10.0.0.2/api.internal?type=A.
+// The query parameters assert the answer:
+//   - type: record type, A (default), AAAA, CNAME, MX, TXT or NS
+//   - expect: records which must be in the answer, comma separated, eg. 10.0.0.1,10.0.0.2
+//   - count: exact number of records, by default at least one is required
+//   - min_ttl, max_ttl: range of the TTLs of the records, eg. 60s
+//
+// The latency of the result is the resolution time. Truncated answers are queried again over TCP.
+type DNSService struct {
+	timeout time.Duration
+}
+
+// dnsCheck is a parsed dns:// URL.
+type dnsCheck struct {
+	resolver string
+	name     string
+	qtype    dns.Type
+	expect   []string
+	count    int // -1 requires at least one record
+	minTTL   time.Duration
+	maxTTL   time.Duration
+}
+
+func NewDNSService(settings model.AppSettings) *DNSService {
+	return &DNSService{timeout: settings.Timeout}
+}
+
+func (D DNSService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", url))
+	check, err := parseDNSCheck(url)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	query := dns.Message{
+		ID:               uint16(rand.UintN(1 << 16)),
+		RecursionDesired: true,
+		Questions:        []dns.Question{{Name: check.name, Type: check.qtype}},
+	}
+
+	start := time.Now()
+	response, size, err := D.exchange("udp", check.resolver, query)
+	if err == nil && response.Truncated {
+		response, size, err = D.exchange("tcp", check.resolver, query)
+	}
+	latency := time.Since(start)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, latency), err
+	}
+	internal.LOGGER.Info(fmt.Sprintf("%s -> %s, %d answers: %s\n", url, response.RCode, len(response.Answers), latency.String()))
+
+	if err := check.verify(response); err != nil {
+		return model.NewHealthCheckResultWithError(err, latency), err
+	}
+	return model.NewSuccessfulResult(latency, uint64(size)), nil
+}
+
+func parseDNSCheck(url string) (dnsCheck, error) {
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return dnsCheck{}, err
+	}
+	check := dnsCheck{
+		resolver: target.Host,
+		name:     dns.Fqdn(strings.TrimPrefix(target.Path, "/")),
+		qtype:    dns.TypeA,
+		count:    -1,
+	}
+	if target.Port() == "" {
+		check.resolver = net.JoinHostPort(target.Hostname(), "53")
+	}
+	query := target.Query()
+	if value := query.Get("type"); value != "" {
+		if check.qtype, err = dns.ParseType(value); err != nil {
+			return dnsCheck{}, err
+		}
+	}
+	if value := query.Get("expect"); value != "" {
+		check.expect = strings.Split(value, ",")
+	}
+	if value := query.Get("count"); value != "" {
+		if check.count, err = strconv.Atoi(value); err != nil || check.count < 0 {
+			return dnsCheck{}, fmt.Errorf("invalid count: %s", value)
+		}
+	}
+	for parameter, ttl := range map[string]*time.Duration{"min_ttl": &check.minTTL, "max_ttl": &check.maxTTL} {
+		if value := query.Get(parameter); value != "" {
+			if *ttl, err = time.ParseDuration(value); err != nil {
+				return dnsCheck{}, fmt.Errorf("invalid %s: %s", parameter, value)
+			}
+		}
+	}
+	return check, nil
+}
+
+// exchange sends the query to the resolver and returns the response and its size.
+func (D DNSService) exchange(network string, resolver string, query dns.Message) (dns.Message, int, error) {
+	packed, err := query.Pack()
+	if err != nil {
+		return dns.Message{}, 0, err
+	}
+	conn, err := net.DialTimeout(network, resolver, D.timeout)
+	if err != nil {
+		return dns.Message{}, 0, err
+	}
+	defer func() { _ = conn.Close() }()
+	if D.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(D.timeout))
+	}
+
+	data := make([]byte, 65535)
+	var size int
+	if network == "tcp" {
+		// Messages over TCP are prefixed by their length
+		if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(packed))), packed...)); err != nil {
+			return dns.Message{}, 0, err
+		}
+		if _, err := io.ReadFull(conn, data[:2]); err != nil {
+			return dns.Message{}, 0, err
+		}
+		size = int(binary.BigEndian.Uint16(data))
+		if _, err := io.ReadFull(conn, data[:size]); err != nil {
+			return dns.Message{}, 0, err
+		}
+	} else {
+		if _, err := conn.Write(packed); err != nil {
+			return dns.Message{}, 0, err
+		}
+		if size, err = conn.Read(data); err != nil {
+			return dns.Message{}, 0, err
+		}
+	}
+
+	response, err := dns.Unpack(data[:size])
+	if err != nil {
+		return dns.Message{}, 0, err
+	}
+	if response.ID != query.ID || !response.Response {
+		return dns.Message{}, 0, fmt.Errorf("unexpected response from %s", resolver)
+	}
+	return response, size, nil
+}
+
+// verify checks the records of the response against the expectations.
+func (c dnsCheck) verify(response dns.Message) error {
+	if response.RCode != dns.RCodeSuccess {
+		return fmt.Errorf("resolving %s %s failed: %s", c.name, c.qtype, response.RCode)
+	}
+	values := make([]string, 0, len(response.Answers))
+	for _, record := range response.Answers {
+		if record.Type != c.qtype {
+			continue // Eg. the CNAME records leading to the A records
+		}
+		values = append(values, normalizeRecord(record.Value))
+		ttl := time.Duration(record.TTL) * time.Second
+		if (c.minTTL > 0 && ttl < c.minTTL) || (c.maxTTL > 0 && ttl > c.maxTTL) {
+			return fmt.Errorf("TTL %s of %s record %s is out of range [%s, %s]", ttl, c.qtype, record.Value, c.minTTL, c.maxTTL)
+		}
+	}
+	switch {
+	case c.count < 0 && len(values) == 0:
+		return fmt.Errorf("no %s records for %s", c.qtype, c.name)
+	case c.count >= 0 && len(values) != c.count:
+		return fmt.Errorf("expected %d %s records for %s, got %d: %s", c.count, c.qtype, c.name, len(values), strings.Join(values, ", "))
+	}
+	for _, expected := range c.expect {
+		if !slices.Contains(values, normalizeRecord(expected)) {
+			return fmt.Errorf("%s record %s not found for %s, got: %s", c.qtype, expected, c.name, strings.Join(values, ", "))
+		}
+	}
+	return nil
+}
+
+// normalizeRecord makes the names in records comparable, eg. "API.internal" and "api.internal.".
+func normalizeRecord(value string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(value), "."))
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxPluginOutput is how much of the output of a plugin is read, like MAX_PLUGIN_OUTPUT_LENGTH of Nagios.
+const maxPluginOutput = 8 * 1024
+
+// Exit codes of Nagios plugins.
+const (
+	pluginOK       = 0
+	pluginWarning  = 1
+	pluginCritical = 2
+)
+
+// ExecService runs the command of exec:// targets as a Nagios plugin,
+// eg. exec:///usr/lib/nagios/plugins/check_disk -w 10% -c 5% -p /.
+// The command is split like a shell would, but without expansions. The exit code is the outcome:
+// 0 OK is UP, 1 WARNING is UP with the DEGRADED check state, 2 CRITICAL is DOWN and anything else,
+// eg. 3 or a missing command, is DOWN with the UNKNOWN check state.
+// The first line of the output and the performance data after | are kept in the result.
+type ExecService struct {
+	timeout time.Duration
+}
+
+func NewExecService(settings model.AppSettings) *ExecService {
+	return &ExecService{timeout: settings.Timeout}
+}
+
+func (E ExecService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", url))
+	command, err := ParseExecURL(url)
+	if err != nil {
+		result := model.NewHealthCheckResultWithError(err, 0)
+		result.CheckState = model.StateUnknown
+		return result, err
+	}
+	ctx := context.Background()
+	if E.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, E.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	stdout := internal.NewLimitedBuffer(maxPluginOutput)
+	cmd.Stdout = stdout
+	// Don't wait forever for children of the plugin keeping the output open
+	cmd.WaitDelay = time.Second
+	start := time.Now()
+	err = cmd.Run()
+	latency := time.Since(start)
+
+	output, perfdata := parsePluginOutput(stdout.String())
+	exitCode := cmd.ProcessState.ExitCode()
+	var exitError *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		err = fmt.Errorf("plugin timed out after %s", E.timeout)
+	case err != nil && !errors.As(err, &exitError):
+		// The plugin didn't run at all
+		exitCode = -1
+	case exitCode == pluginOK || exitCode == pluginWarning:
+		err = nil
+	case output != "":
+		err = errors.New(output)
+	default:
+		err = fmt.Errorf("plugin exited with %d", exitCode)
+	}
+	internal.LOGGER.Info(fmt.Sprintf("%s -> exit code %d: %s\n", url, exitCode, latency.String()))
+
+	var result model.HealthCheckResult
+	if err != nil {
+		result = model.NewHealthCheckResultWithError(err, latency)
+		if ctx.Err() == nil && exitCode != pluginCritical {
+			result.CheckState = model.StateUnknown
+		}
+	} else {
+		result = model.NewSuccessfulResult(latency, uint64(len(stdout.String())))
+		if exitCode == pluginWarning {
+			result.CheckState = model.StateDegraded
+		}
+	}
+	result.Output, result.Perfdata = output, perfdata
+	return result, err
+}
+
+// ParseExecURL returns the command of an exec:// URL and its arguments.
+func ParseExecURL(url string) ([]string, error) {
+	command, err := splitCommand(strings.TrimPrefix(url, "exec://"))
+	if err != nil {
+		return nil, err
+	}
+	if len(command) == 0 {
+		return nil, errors.New("exec URL must have a command, eg. exec:///usr/lib/nagios/plugins/check_load -w 5 -c 10")
+	}
+	return command, nil
+}
+
+// splitCommand splits the command line into words separated by spaces,
+// single and double quotes group words and backslashes escape the next character.
+func splitCommand(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, char := range line {
+		switch {
+		case escaped:
+			word.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(char)
+		case char == '\'' || char == '"':
+			quote, inWord = char, true
+		case unicode.IsSpace(char):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(char)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape in the command")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// parsePluginOutput returns the first line of the output without the performance data, and the
+// performance data of all lines: TEXT | PERFDATA, optionally followed by long output | MORE PERFDATA.
+func parsePluginOutput(stdout string) (string, []model.Perfdata) {
+	firstLine, longOutput, _ := strings.Cut(stdout, "\n")
+	text, perfdata, _ := strings.Cut(firstLine, "|")
+	if _, more, found := strings.Cut(longOutput, "|"); found {
+		perfdata += " " + strings.Join(strings.Fields(more), " ")
+	}
+	return strings.TrimSpace(text), parsePerfdata(perfdata)
+}
+
+// parsePerfdata parses 'label'=value[UOM];[warn];[crit];[min];[max] items separated by whitespace.
+// Items without a numeric value, eg. U for undetermined, are skipped.
+func parsePerfdata(perfdata string) []model.Perfdata {
+	var items []model.Perfdata
+	rest := strings.TrimSpace(perfdata)
+	for rest != "" {
+		var label, item string
+		if strings.HasPrefix(rest, "'") {
+			// Quoted labels may contain spaces
+			end := strings.Index(rest[1:], "'=")
+			if end < 0 {
+				break
+			}
+			label, rest = rest[1:end+1], rest[end+3:]
+		} else {
+			var found bool
+			if label, rest, found = strings.Cut(rest, "="); !found {
+				break
+			}
+		}
+		item, rest, _ = strings.Cut(rest, " ")
+		rest = strings.TrimSpace(rest)
+
+		fields := strings.Split(strings.TrimSpace(item), ";")
+		number := strings.TrimRightFunc(fields[0], func(char rune) bool { return !unicode.IsDigit(char) && char != '.' })
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			continue
+		}
+		fields = append(fields, make([]string, 5-min(len(fields), 5))...)
+		items = append(items, model.Perfdata{
+			Label:    strings.TrimSpace(label),
+			Value:    value,
+			Unit:     fields[0][len(number):],
+			Warning:  fields[1],
+			Critical: fields[2],
+			Min:      fields[3],
+			Max:      fields[4],
+		})
+	}
+	return items
+}
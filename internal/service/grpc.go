@@ -0,0 +1,259 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	urllib "net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// grpcHealthPath is the method of the standard gRPC Health Checking Protocol.
+const grpcHealthPath = "/grpc.health.v1.Health/Check"
+
+// Serving statuses of grpc.health.v1.HealthCheckResponse.
+var grpcServingStatuses = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+var grpcCodes = []string{
+	"OK", "CANCELED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND", "ALREADY_EXISTS",
+	"PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE",
+	"UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// GRPCService calls grpc.health.v1.Health/Check, eg. grpc://backend.internal:50051/payments.Payments.
+// The path is the checked service, empty for the overall health of the server. Only SERVING is healthy,
+// NOT_SERVING is DOWN and UNKNOWN or SERVICE_UNKNOWN are inconclusive, their CheckState is UNKNOWN.
+// The query parameters configure the call:
+//   - tls=true: connect over TLS, insecure_skip_verify=true and server_name=... adjust its verification
+//   - metadata=key:value: sent with the call, can be repeated, eg. metadata=x-tenant:shop
+//   - metadata_env=key:ENV_VARIABLE: sent with the value of the environment variable, for credentials,
+//     eg. metadata_env=authorization:GRPC_TOKEN
+//
+// Plaintext calls use HTTP/2 with prior knowledge (h2c), like gRPC clients do.
+type GRPCService struct {
+	timeout    time.Duration
+	mu         *sync.Mutex
+	transports map[grpcTransportKey]*http.Transport // Connections are reused by the checks with the same TLS settings
+}
+
+type grpcTransportKey struct {
+	tls                bool
+	insecureSkipVerify bool
+	serverName         string
+}
+
+func NewGRPCService(settings model.AppSettings) *GRPCService {
+	return &GRPCService{
+		timeout:    settings.Timeout,
+		mu:         &sync.Mutex{},
+		transports: make(map[grpcTransportKey]*http.Transport),
+	}
+}
+
+// transport returns the transport for the TLS settings, created on the first use.
+func (G GRPCService) transport(key grpcTransportKey) *http.Transport {
+	G.mu.Lock()
+	defer G.mu.Unlock()
+	if transport, exists := G.transports[key]; exists {
+		return transport
+	}
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: key.insecureSkipVerify,
+			ServerName:         key.serverName,
+		},
+		Protocols: &http.Protocols{},
+	}
+	transport.Protocols.SetHTTP2(key.tls)
+	transport.Protocols.SetUnencryptedHTTP2(!key.tls)
+	G.transports[key] = transport
+	return transport
+}
+
+func (G GRPCService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", redactURL(url)))
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	query := target.Query()
+	useTLS := query.Get("tls") == "true"
+	transport := G.transport(grpcTransportKey{
+		tls:                useTLS,
+		insecureSkipVerify: query.Get("insecure_skip_verify") == "true",
+		serverName:         query.Get("server_name"),
+	})
+
+	endpoint := "http://" + target.Host + grpcHealthPath
+	if useTLS {
+		endpoint = "https://" + target.Host + grpcHealthPath
+	}
+	ctx := context.Background()
+	if G.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, G.timeout)
+		defer cancel()
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint,
+		bytes.NewReader(grpcFrame(healthCheckRequest(strings.Trim(target.Path, "/")))))
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("TE", "trailers")
+	if G.timeout > 0 {
+		request.Header.Set("Grpc-Timeout", strconv.FormatInt(G.timeout.Milliseconds(), 10)+"m")
+	}
+	for _, metadata := range query["metadata"] {
+		key, value, found := strings.Cut(metadata, ":")
+		if !found {
+			err := fmt.Errorf("invalid metadata %q, expected key:value", metadata)
+			return model.NewHealthCheckResultWithError(err, 0), err
+		}
+		request.Header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	for _, metadata := range query["metadata_env"] {
+		key, name, found := strings.Cut(metadata, ":")
+		if !found {
+			err := fmt.Errorf("invalid metadata_env %q, expected key:ENV_VARIABLE", metadata)
+			return model.NewHealthCheckResultWithError(err, 0), err
+		}
+		request.Header.Add(strings.TrimSpace(key), os.Getenv(strings.TrimSpace(name)))
+	}
+
+	start := time.Now()
+	response, err := (&http.Client{Transport: transport}).Do(request)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, time.Since(start)), err
+	}
+	defer func() { _ = response.Body.Close() }()
+	body, err := io.ReadAll(response.Body)
+	latency := time.Since(start)
+	if err == nil {
+		err = grpcStatusError(response)
+	}
+	var status string
+	if err == nil {
+		status, err = servingStatus(body)
+	}
+	if err == nil && status != "SERVING" {
+		err = fmt.Errorf("health status %s", status)
+	}
+	result := model.NewSuccessfulResult(latency, uint64(len(body)))
+	if err != nil {
+		result = model.NewHealthCheckResultWithError(err, latency)
+	}
+	if status == "UNKNOWN" || status == "SERVICE_UNKNOWN" {
+		result.CheckState = model.StateUnknown
+	}
+	result.CertExpiry = certificateExpiry(response.TLS)
+	internal.LOGGER.Info(fmt.Sprintf("%s -> %s: %s\n", redactURL(url), status, latency.String()))
+	return result, err
+}
+
+// grpcStatusError returns the error of the call, its status is in the trailers
+// or in the headers for responses without a body.
+func grpcStatusError(response *http.Response) error {
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", response.StatusCode)
+	}
+	code := response.Trailer.Get("Grpc-Status")
+	message := response.Trailer.Get("Grpc-Message")
+	if code == "" {
+		code = response.Header.Get("Grpc-Status")
+		message = response.Header.Get("Grpc-Message")
+	}
+	if code == "" || code == "0" {
+		return nil
+	}
+	name := "code " + code
+	if value, err := strconv.Atoi(code); err == nil && value >= 0 && value < len(grpcCodes) {
+		name = grpcCodes[value]
+	}
+	if decoded, err := urllib.PathUnescape(message); err == nil {
+		message = decoded
+	}
+	return fmt.Errorf("gRPC status %s: %s", name, message)
+}
+
+// grpcFrame prefixes the message by the uncompressed flag and its length.
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// healthCheckRequest encodes grpc.health.v1.HealthCheckRequest{service}.
+func healthCheckRequest(service string) []byte {
+	if service == "" {
+		return nil
+	}
+	message := []byte{0x0A} // Field 1, length delimited
+	message = binary.AppendUvarint(message, uint64(len(service)))
+	return append(message, service...)
+}
+
+// servingStatus decodes the status of grpc.health.v1.HealthCheckResponse from the response frame.
+func servingStatus(body []byte) (string, error) {
+	if len(body) < 5 {
+		return "", errors.New("empty gRPC response")
+	}
+	if body[0] != 0 {
+		return "", errors.New("compressed gRPC responses are not supported")
+	}
+	length := int(binary.BigEndian.Uint32(body[1:]))
+	if len(body) < 5+length {
+		return "", errors.New("truncated gRPC response")
+	}
+	message := body[5 : 5+length]
+	status := uint64(0) // Missing field is the default UNKNOWN
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return "", errors.New("invalid gRPC response")
+		}
+		message = message[n:]
+		var value uint64
+		switch key & 7 {
+		case 0:
+			value, n = binary.Uvarint(message)
+		case 1:
+			n = 8
+		case 2:
+			var size uint64
+			size, n = binary.Uvarint(message)
+			n += int(size)
+		case 5:
+			n = 4
+		default:
+			n = -1
+		}
+		if n <= 0 || n > len(message) {
+			return "", errors.New("invalid gRPC response")
+		}
+		message = message[n:]
+		if key == 1<<3 { // Field 1, varint
+			status = value
+		}
+	}
+	if name, ok := grpcServingStatuses[status]; ok {
+		return name, nil
+	}
+	return "STATUS_" + strconv.FormatUint(status, 10), nil
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	urllib "net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// mailPorts are the default ports of the mail (and file transfer) protocols, the s suffix means implicit TLS.
+var mailPorts = map[string]string{
+	"smtp": "25", "smtps": "465", "imap": "143", "imaps": "993", "ftp": "21",
+}
+
+// MailService checks the conversation of smtp://, smtps://, imap://, imaps:// and ftp:// servers:
+// connect, read the greeting banner, EHLO (SMTP only), optionally upgrade to TLS, then QUIT.
+// The query parameters:
+//   - starttls=true: upgrade with STARTTLS (AUTH TLS for FTP), which must succeed
+//   - insecure_skip_verify=true, server_name: verification of the certificate
+//   - expect: the banner must contain it
+//   - helo: name sent with EHLO, the hostname by default
+//
+// The latency of the result is the whole conversation, the duration of each step is kept in the phases.
+type MailService struct {
+	dialer   *net.Dialer
+	timeout  time.Duration
+	hostname string
+}
+
+// mailSession is a conversation with the server, recording the duration of its phases.
+type mailSession struct {
+	conn   net.Conn
+	text   *textproto.Conn
+	tls    *tls.ConnectionState
+	phases []model.Phase
+	last   time.Time
+}
+
+func NewMailService(settings model.AppSettings) *MailService {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return &MailService{
+		dialer:   &net.Dialer{Timeout: settings.Timeout},
+		timeout:  settings.Timeout,
+		hostname: hostname,
+	}
+}
+
+func (M MailService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", url))
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	query := target.Query()
+	config := &tls.Config{ServerName: target.Hostname(), InsecureSkipVerify: query.Get("insecure_skip_verify") == "true"}
+	if serverName := query.Get("server_name"); serverName != "" {
+		config.ServerName = serverName
+	}
+
+	start := time.Now()
+	session := &mailSession{last: start}
+	banner, err := M.converse(session, target, config)
+	latency := time.Since(start)
+	if session.conn != nil {
+		_ = session.conn.Close()
+	}
+
+	var result model.HealthCheckResult
+	if err != nil {
+		result = model.NewHealthCheckResultWithError(err, latency)
+	} else {
+		internal.LOGGER.Info(fmt.Sprintf("%s -> %s: %s\n", url, banner, latency.String()))
+		result = model.NewSuccessfulResult(latency, uint64(len(banner)))
+	}
+	result.Phases = session.phases
+	result.CertExpiry = certificateExpiry(session.tls)
+	return result, err
+}
+
+// converse runs the conversation with the server and returns its banner.
+func (M MailService) converse(session *mailSession, target *urllib.URL, config *tls.Config) (string, error) {
+	scheme := strings.ToLower(target.Scheme)
+	port, known := mailPorts[scheme]
+	if !known {
+		return "", fmt.Errorf("unsupported URL scheme: %s", target.Scheme)
+	}
+	address := target.Host
+	if target.Port() == "" {
+		address = net.JoinHostPort(target.Hostname(), port)
+	}
+	conn, err := M.dialer.Dial("tcp", address)
+	if err != nil {
+		return "", err
+	}
+	if M.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(M.timeout))
+	}
+	session.use(conn)
+	session.phase("connect")
+	if strings.HasSuffix(scheme, "s") {
+		if err := session.startTLS(config); err != nil {
+			return "", err
+		}
+		session.phase("tls")
+	}
+
+	query := target.Query()
+	starttls := query.Get("starttls") == "true"
+	var banner string
+	switch strings.TrimSuffix(scheme, "s") {
+	case "smtp":
+		banner, err = session.smtp(starttls, config, query.Get("helo"), M.hostname)
+	case "imap":
+		banner, err = session.imap(starttls, config)
+	case "ftp":
+		banner, err = session.ftp(starttls, config)
+	}
+	if err == nil && !strings.Contains(banner, query.Get("expect")) {
+		err = fmt.Errorf("expected %q in the banner %q", query.Get("expect"), banner)
+	}
+	return banner, err
+}
+
+func (s *mailSession) smtp(starttls bool, config *tls.Config, helo string, hostname string) (string, error) {
+	_, banner, err := s.text.ReadResponse(220)
+	if err != nil {
+		return banner, mailFailure("unexpected greeting", err)
+	}
+	s.phase("banner")
+	if helo == "" {
+		helo = hostname
+	}
+	if err := s.text.PrintfLine("EHLO %s", helo); err != nil {
+		return banner, err
+	}
+	_, extensions, err := s.text.ReadResponse(250)
+	if err != nil {
+		return banner, mailFailure("EHLO failed", err)
+	}
+	s.phase("ehlo")
+	if starttls {
+		if !strings.Contains(strings.ToUpper(extensions), "STARTTLS") {
+			return banner, errors.New("STARTTLS is not offered")
+		}
+		if err := s.upgrade("STARTTLS", 220, config); err != nil {
+			return banner, err
+		}
+	}
+	s.quit("QUIT", 221)
+	return banner, nil
+}
+
+func (s *mailSession) ftp(starttls bool, config *tls.Config) (string, error) {
+	_, banner, err := s.text.ReadResponse(220)
+	if err != nil {
+		return banner, mailFailure("unexpected greeting", err)
+	}
+	s.phase("banner")
+	if starttls {
+		if err := s.upgrade("AUTH TLS", 234, config); err != nil {
+			return banner, err
+		}
+	}
+	s.quit("QUIT", 221)
+	return banner, nil
+}
+
+func (s *mailSession) imap(starttls bool, config *tls.Config) (string, error) {
+	banner, err := s.text.ReadLine()
+	if err != nil {
+		return banner, mailFailure("unexpected greeting", err)
+	}
+	if !strings.HasPrefix(banner, "* OK") && !strings.HasPrefix(banner, "* PREAUTH") {
+		return banner, fmt.Errorf("unexpected greeting: %s", banner)
+	}
+	banner = strings.TrimPrefix(banner, "* ")
+	s.phase("banner")
+	if starttls {
+		if err := s.text.PrintfLine("a1 STARTTLS"); err != nil {
+			return banner, err
+		}
+		status, err := s.imapTagged("a1")
+		if err != nil {
+			return banner, mailFailure("STARTTLS failed", err)
+		}
+		if !strings.HasPrefix(status, "OK") {
+			return banner, fmt.Errorf("STARTTLS failed: %s", status)
+		}
+		if err := s.startTLS(config); err != nil {
+			return banner, err
+		}
+		s.phase("starttls")
+	}
+	if s.text.PrintfLine("a2 LOGOUT") == nil {
+		if _, err := s.imapTagged("a2"); err == nil {
+			s.phase("quit")
+		}
+	}
+	return banner, nil
+}
+
+// imapTagged reads the responses up to the one tagged with tag and returns its status, eg. OK Begin TLS negotiation.
+func (s *mailSession) imapTagged(tag string) (string, error) {
+	for {
+		line, err := s.text.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if status, found := strings.CutPrefix(line, tag+" "); found {
+			return status, nil
+		}
+	}
+}
+
+// upgrade sends the command asking the server to start TLS and performs the handshake.
+func (s *mailSession) upgrade(command string, code int, config *tls.Config) error {
+	if err := s.text.PrintfLine("%s", command); err != nil {
+		return err
+	}
+	if _, _, err := s.text.ReadResponse(code); err != nil {
+		return mailFailure(command+" failed", err)
+	}
+	if err := s.startTLS(config); err != nil {
+		return err
+	}
+	s.phase("starttls")
+	return nil
+}
+
+// quit says goodbye, servers closing the connection right away don't fail the check.
+func (s *mailSession) quit(command string, code int) {
+	if s.text.PrintfLine("%s", command) != nil {
+		return
+	}
+	if _, _, err := s.text.ReadResponse(code); err == nil {
+		s.phase("quit")
+	}
+}
+
+func (s *mailSession) startTLS(config *tls.Config) error {
+	tlsConn := tls.Client(s.conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	state := tlsConn.ConnectionState()
+	s.tls = &state
+	s.use(tlsConn)
+	return nil
+}
+
+func (s *mailSession) use(conn net.Conn) {
+	s.conn = conn
+	s.text = textproto.NewConn(conn)
+}
+
+// phase records the time since the end of the previous phase.
+func (s *mailSession) phase(name string) {
+	now := time.Now()
+	s.phases = append(s.phases, model.Phase{Name: name, Duration: now.Sub(s.last)})
+	s.last = now
+}
+
+// mailFailure describes the failed step with the reply of the server, eg. unexpected greeting: 554 5.3.2 unavailable.
+func mailFailure(step string, err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return fmt.Errorf("%s: %d %s", step, reply.Code, reply.Msg)
+	}
+	return fmt.Errorf("%s: %w", step, err)
+}
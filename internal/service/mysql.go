@@ -0,0 +1,318 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	urllib "net/url"
+	"strings"
+	"time"
+)
+
+// Capabilities of the client sent in the handshake response.
+const (
+	mysqlLongPassword     = 0x00000001
+	mysqlConnectWithDB    = 0x00000008
+	mysqlProtocol41       = 0x00000200
+	mysqlTransactions     = 0x00002000
+	mysqlSecureConnection = 0x00008000
+	mysqlPluginAuth       = 0x00080000
+)
+
+// Error codes of the server which mean the credentials were rejected.
+var mysqlAuthErrors = map[uint16]bool{
+	1044: true, // ER_DBACCESS_DENIED_ERROR
+	1045: true, // ER_ACCESS_DENIED_ERROR
+	1130: true, // ER_HOST_NOT_PRIVILEGED
+	1251: true, // ER_NOT_SUPPORTED_AUTH_MODE
+	1698: true, // ER_ACCESS_DENIED_NO_PASSWORD_ERROR
+}
+
+// MySQLService connects to mysql://user@host:3306/db and runs SELECT 1.
+// The mysql_native_password and caching_sha2_password (fast path only, the full authentication needs TLS)
+// plugins are supported, the password is read from the environment or a file, see credentials.
+type MySQLService struct {
+	dialer  *net.Dialer
+	timeout time.Duration
+}
+
+// mysqlError is an ERR packet of the server.
+type mysqlError struct {
+	Code    uint16
+	Message string
+}
+
+func (e *mysqlError) Error() string {
+	return fmt.Sprintf("%s (error %d)", e.Message, e.Code)
+}
+
+// mysqlConn reads and writes packets, keeping track of their sequence number.
+type mysqlConn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	sequence byte
+}
+
+func NewMySQLService(settings model.AppSettings) *MySQLService {
+	return &MySQLService{
+		dialer:  &net.Dialer{Timeout: settings.Timeout},
+		timeout: settings.Timeout,
+	}
+}
+
+func (M MySQLService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", redactURL(url)))
+	start := time.Now()
+	err := M.query(url)
+	latency := time.Since(start)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, latency), err
+	}
+	internal.LOGGER.Info(fmt.Sprintf("%s -> SELECT 1: %s\n", redactURL(url), latency.String()))
+	return model.NewSuccessfulResult(latency, 0), nil
+}
+
+func (M MySQLService) query(url string) error {
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return err
+	}
+	user, password, err := credentials(target)
+	if err != nil {
+		return err
+	}
+	if user == "" {
+		return errors.New("missing user")
+	}
+	conn, err := dialDataStore(M.dialer, target, "3306", M.timeout)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	client := &mysqlConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	greeting, err := client.read()
+	if err != nil {
+		return err
+	}
+	if len(greeting) > 0 && greeting[0] == 0xFF {
+		return mysqlFailure(greeting)
+	}
+	plugin, scramble, err := parseMySQLGreeting(greeting)
+	if err != nil {
+		return err
+	}
+	if err := client.write(mysqlHandshakeResponse(user, password, strings.Trim(target.Path, "/"), plugin, scramble)); err != nil {
+		return ioFailure(err)
+	}
+	if err := client.authenticate(password, plugin, scramble); err != nil {
+		return err
+	}
+
+	client.sequence = 0
+	if err := client.write(append([]byte{0x03}, "SELECT 1"...)); err != nil {
+		return ioFailure(err)
+	}
+	if err := client.result(); err != nil {
+		return err
+	}
+	client.sequence = 0
+	_ = client.write([]byte{0x01})
+	return nil
+}
+
+// authenticate reads the replies to the handshake response until the OK packet,
+// switching the authentication plugin if the server asks to.
+func (c *mysqlConn) authenticate(password string, plugin string, scramble []byte) error {
+	for {
+		packet, err := c.read()
+		if err != nil {
+			return err
+		}
+		if len(packet) == 0 {
+			return failure(FailureProtocol, "empty packet during authentication")
+		}
+		switch packet[0] {
+		case 0x00:
+			return nil
+		case 0xFF:
+			return mysqlFailure(packet)
+		case 0xFE: // AuthSwitchRequest
+			name, data, _ := bytes.Cut(packet[1:], []byte{0})
+			plugin, scramble = string(name), bytes.TrimSuffix(data, []byte{0})
+			response, err := mysqlScramble(plugin, password, scramble)
+			if err != nil {
+				return err
+			}
+			if err := c.write(response); err != nil {
+				return ioFailure(err)
+			}
+		case 0x01: // AuthMoreData of caching_sha2_password
+			if len(packet) > 1 && packet[1] == 0x04 {
+				return failure(FailureAuth, "caching_sha2_password full authentication requires TLS, connect once with another client to fill the cache")
+			}
+		default:
+			return failure(FailureProtocol, "unexpected packet 0x%02x during authentication", packet[0])
+		}
+	}
+}
+
+// result reads the response to the query: the column definitions and rows, each terminated by an EOF packet.
+func (c *mysqlConn) result() error {
+	packet, err := c.read()
+	if err != nil {
+		return err
+	}
+	if len(packet) == 0 {
+		return failure(FailureProtocol, "empty response to the query")
+	}
+	switch packet[0] {
+	case 0x00:
+		return nil
+	case 0xFF:
+		return mysqlFailure(packet)
+	}
+	for terminators := 0; terminators < 2; {
+		packet, err := c.read()
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(packet) > 0 && packet[0] == 0xFF:
+			return mysqlFailure(packet)
+		case len(packet) > 0 && len(packet) < 9 && packet[0] == 0xFE:
+			terminators++
+		}
+	}
+	return nil
+}
+
+func (c *mysqlConn) read() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return nil, ioFailure(err)
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	c.sequence = header[3] + 1
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, packet); err != nil {
+		return nil, ioFailure(err)
+	}
+	return packet, nil
+}
+
+func (c *mysqlConn) write(payload []byte) error {
+	length := len(payload)
+	header := []byte{byte(length), byte(length >> 8), byte(length >> 16), c.sequence}
+	c.sequence++
+	_, err := c.conn.Write(append(header, payload...))
+	return err
+}
+
+// parseMySQLGreeting returns the authentication plugin and scramble of the initial handshake (protocol version 10).
+func parseMySQLGreeting(greeting []byte) (string, []byte, error) {
+	if len(greeting) == 0 || greeting[0] != 10 {
+		return "", nil, failure(FailureProtocol, "unsupported handshake, not a MySQL server?")
+	}
+	_, rest, found := bytes.Cut(greeting[1:], []byte{0}) // Server version
+	// Connection id, first part of the scramble, filler, capabilities, charset, status, capabilities, scramble length, reserved
+	if !found || len(rest) < 31 {
+		return "", nil, failure(FailureProtocol, "truncated handshake")
+	}
+	scramble := append([]byte{}, rest[4:12]...)
+	capabilities := uint32(binary.LittleEndian.Uint16(rest[13:15])) | uint32(binary.LittleEndian.Uint16(rest[18:20]))<<16
+	rest = rest[31:]
+	length := 13
+	if len(rest) < length {
+		length = len(rest)
+	}
+	scramble = append(scramble, bytes.TrimSuffix(rest[:length], []byte{0})...)
+	plugin := "mysql_native_password"
+	if capabilities&mysqlPluginAuth != 0 {
+		name, _, _ := bytes.Cut(rest[length:], []byte{0})
+		if len(name) > 0 {
+			plugin = string(name)
+		}
+	}
+	return plugin, scramble, nil
+}
+
+// mysqlHandshakeResponse builds the HandshakeResponse41 packet.
+func mysqlHandshakeResponse(user string, password string, database string, plugin string, scramble []byte) []byte {
+	capabilities := uint32(mysqlLongPassword | mysqlProtocol41 | mysqlTransactions | mysqlSecureConnection | mysqlPluginAuth)
+	if database != "" {
+		capabilities |= mysqlConnectWithDB
+	}
+	auth, err := mysqlScramble(plugin, password, scramble)
+	if err != nil {
+		// Let the server ask for a supported plugin
+		plugin = "mysql_native_password"
+		auth, _ = mysqlScramble(plugin, password, scramble)
+	}
+	packet := binary.LittleEndian.AppendUint32(nil, capabilities)
+	packet = binary.LittleEndian.AppendUint32(packet, 1<<24) // Max packet size
+	packet = append(packet, 45)                              // utf8mb4_general_ci
+	packet = append(packet, make([]byte, 23)...)
+	packet = append(append(packet, user...), 0)
+	packet = append(append(packet, byte(len(auth))), auth...)
+	if database != "" {
+		packet = append(append(packet, database...), 0)
+	}
+	return append(append(packet, plugin...), 0)
+}
+
+// mysqlScramble computes the authentication response of the plugin, empty for an empty password.
+func mysqlScramble(plugin string, password string, scramble []byte) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+	switch plugin {
+	case "mysql_native_password":
+		// SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+		hash := sha1.Sum([]byte(password))
+		double := sha1.Sum(hash[:])
+		mixed := sha1.Sum(append(append([]byte{}, scramble...), double[:]...))
+		for i := range hash {
+			hash[i] ^= mixed[i]
+		}
+		return hash[:], nil
+	case "caching_sha2_password":
+		// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + scramble)
+		hash := sha256.Sum256([]byte(password))
+		double := sha256.Sum256(hash[:])
+		mixed := sha256.Sum256(append(double[:], scramble...))
+		for i := range hash {
+			hash[i] ^= mixed[i]
+		}
+		return hash[:], nil
+	default:
+		return nil, failure(FailureAuth, "unsupported authentication plugin %s", plugin)
+	}
+}
+
+// mysqlFailure classifies an ERR packet, rejected credentials are auth failures.
+// Others, eg. an unknown database or too many connections, fail the query.
+func mysqlFailure(packet []byte) error {
+	kind := FailureQuery
+	response := &mysqlError{}
+	if len(packet) >= 3 {
+		response.Code = binary.LittleEndian.Uint16(packet[1:3])
+		message := packet[3:]
+		if len(message) >= 6 && message[0] == '#' {
+			message = message[6:] // SQL state
+		}
+		response.Message = string(message)
+	}
+	if mysqlAuthErrors[response.Code] {
+		kind = FailureAuth
+	}
+	return &CheckError{Kind: kind, Err: response}
+}
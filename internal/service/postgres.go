@@ -0,0 +1,305 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	urllib "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// postgresProtocol is version 3.0 of the frontend/backend protocol.
+const postgresProtocol = 196608
+
+// PostgresService connects to postgres://user@host:5432/db and runs SELECT 1.
+// Cleartext, MD5 and SCRAM-SHA-256 authentication are supported, the password is read
+// from the environment or a file, see credentials. The connection is not encrypted.
+type PostgresService struct {
+	dialer  *net.Dialer
+	timeout time.Duration
+}
+
+// postgresError is an ErrorResponse of the server.
+type postgresError struct {
+	Code    string // SQLSTATE, eg. 28P01 for a wrong password
+	Message string
+}
+
+func (e *postgresError) Error() string {
+	return fmt.Sprintf("%s (SQLSTATE %s)", e.Message, e.Code)
+}
+
+func NewPostgresService(settings model.AppSettings) *PostgresService {
+	return &PostgresService{
+		dialer:  &net.Dialer{Timeout: settings.Timeout},
+		timeout: settings.Timeout,
+	}
+}
+
+func (P PostgresService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", redactURL(url)))
+	start := time.Now()
+	err := P.query(url)
+	latency := time.Since(start)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, latency), err
+	}
+	internal.LOGGER.Info(fmt.Sprintf("%s -> SELECT 1: %s\n", redactURL(url), latency.String()))
+	return model.NewSuccessfulResult(latency, 0), nil
+}
+
+func (P PostgresService) query(url string) error {
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return err
+	}
+	user, password, err := credentials(target)
+	if err != nil {
+		return err
+	}
+	if user == "" {
+		return errors.New("missing user")
+	}
+	database := strings.Trim(target.Path, "/")
+	if database == "" {
+		database = user
+	}
+	conn, err := dialDataStore(P.dialer, target, "5432", P.timeout)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+
+	startup := binary.BigEndian.AppendUint32(nil, postgresProtocol)
+	for _, parameter := range []string{"user", user, "database", database, "application_name", "GoHealthChecker"} {
+		startup = append(append(startup, parameter...), 0)
+	}
+	startup = append(startup, 0)
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(startup)+4)), startup...)); err != nil {
+		return ioFailure(err)
+	}
+	if err := postgresAuthenticate(conn, reader, user, password); err != nil {
+		return err
+	}
+	if err := postgresReady(reader); err != nil {
+		return err
+	}
+
+	if err := postgresWrite(conn, 'Q', append([]byte("SELECT 1"), 0)); err != nil {
+		return ioFailure(err)
+	}
+	if err := postgresReady(reader); err != nil {
+		return err
+	}
+	_ = postgresWrite(conn, 'X', nil)
+	return nil
+}
+
+// postgresAuthenticate answers the authentication requests of the server until it accepts the password.
+func postgresAuthenticate(conn net.Conn, reader *bufio.Reader, user string, password string) error {
+	var scram *scramClient
+	for {
+		kind, payload, err := postgresRead(reader)
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case 'E':
+			return postgresFailure(payload)
+		case 'N':
+			continue
+		case 'R':
+		default:
+			return failure(FailureProtocol, "unexpected message %q during authentication", kind)
+		}
+		if len(payload) < 4 {
+			return failure(FailureProtocol, "invalid authentication request")
+		}
+		request, data := binary.BigEndian.Uint32(payload), payload[4:]
+		var response []byte
+		switch request {
+		case 0: // AuthenticationOk
+			return nil
+		case 3: // AuthenticationCleartextPassword
+			response = append([]byte(password), 0)
+		case 5: // AuthenticationMD5Password
+			if len(data) < 4 {
+				return failure(FailureProtocol, "invalid MD5 salt")
+			}
+			inner := md5.Sum([]byte(password + user))
+			outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), data[:4]...))
+			response = append([]byte("md5"+hex.EncodeToString(outer[:])), 0)
+		case 10: // AuthenticationSASL
+			if !bytes.Contains(data, []byte("SCRAM-SHA-256\x00")) {
+				return failure(FailureAuth, "no supported SASL mechanism in %q", data)
+			}
+			scram = newScramClient(password)
+			first := scram.first()
+			response = append([]byte("SCRAM-SHA-256\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(first)))...)
+			response = append(response, first...)
+		case 11: // AuthenticationSASLContinue
+			if scram == nil {
+				return failure(FailureProtocol, "unexpected SASL continuation")
+			}
+			final, err := scram.final(string(data))
+			if err != nil {
+				return &CheckError{Kind: FailureAuth, Err: err}
+			}
+			response = []byte(final)
+		case 12: // AuthenticationSASLFinal
+			if scram == nil || !scram.verify(string(data)) {
+				return failure(FailureAuth, "invalid SCRAM server signature")
+			}
+			continue
+		default:
+			return failure(FailureAuth, "unsupported authentication method %d", request)
+		}
+		if err := postgresWrite(conn, 'p', response); err != nil {
+			return ioFailure(err)
+		}
+	}
+}
+
+// postgresReady reads messages until ReadyForQuery, eg. the result of the query.
+func postgresReady(reader *bufio.Reader) error {
+	for {
+		messageType, payload, err := postgresRead(reader)
+		if err != nil {
+			return err
+		}
+		switch messageType {
+		case 'Z':
+			return nil
+		case 'E':
+			return postgresFailure(payload)
+		}
+	}
+}
+
+func postgresWrite(conn net.Conn, kind byte, payload []byte) error {
+	message := binary.BigEndian.AppendUint32([]byte{kind}, uint32(len(payload)+4))
+	_, err := conn.Write(append(message, payload...))
+	return err
+}
+
+func postgresRead(reader *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, ioFailure(err)
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length < 4 || length > 1<<20 {
+		return 0, nil, failure(FailureProtocol, "invalid message length %d", length)
+	}
+	payload := make([]byte, length-4)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, nil, ioFailure(err)
+	}
+	return header[0], payload, nil
+}
+
+// postgresFailure classifies an ErrorResponse, errors of the class 28 (invalid authorization) are auth failures.
+// Others, eg. a missing database or too many connections, fail the query.
+func postgresFailure(payload []byte) error {
+	kind := FailureQuery
+	response := &postgresError{}
+	for _, field := range bytes.Split(payload, []byte{0}) {
+		if len(field) < 2 {
+			continue
+		}
+		switch field[0] {
+		case 'C':
+			response.Code = string(field[1:])
+		case 'M':
+			response.Message = string(field[1:])
+		}
+	}
+	if strings.HasPrefix(response.Code, "28") {
+		kind = FailureAuth
+	}
+	return &CheckError{Kind: kind, Err: response}
+}
+
+// scramClient computes the messages of SCRAM-SHA-256 (RFC 7677) as used by PostgreSQL.
+type scramClient struct {
+	password        string
+	nonce           string
+	authMessage     string
+	serverSignature []byte
+}
+
+func newScramClient(password string) *scramClient {
+	nonce := make([]byte, 18)
+	_, _ = rand.Read(nonce)
+	return &scramClient{password: password, nonce: base64.StdEncoding.EncodeToString(nonce)}
+}
+
+// first returns the client-first-message, the user name is ignored by PostgreSQL.
+func (s *scramClient) first() string {
+	return "n,,n=,r=" + s.nonce
+}
+
+// final returns the client-final-message with the proof for the server-first-message.
+func (s *scramClient) final(serverFirst string) (string, error) {
+	attributes := map[string]string{}
+	for _, attribute := range strings.Split(serverFirst, ",") {
+		if name, value, found := strings.Cut(attribute, "="); found {
+			attributes[name] = value
+		}
+	}
+	nonce := attributes["r"]
+	if !strings.HasPrefix(nonce, s.nonce) {
+		return "", errors.New("invalid SCRAM nonce")
+	}
+	salt, err := base64.StdEncoding.DecodeString(attributes["s"])
+	if err != nil {
+		return "", fmt.Errorf("invalid SCRAM salt: %w", err)
+	}
+	iterations, err := strconv.Atoi(attributes["i"])
+	if err != nil || iterations < 1 {
+		return "", fmt.Errorf("invalid SCRAM iteration count %q", attributes["i"])
+	}
+
+	salted, err := pbkdf2.Key(sha256.New, s.password, salt, iterations, sha256.Size)
+	if err != nil {
+		return "", err
+	}
+	clientKey := scramHMAC(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	withoutProof := "c=biws,r=" + nonce
+	s.authMessage = "n=,r=" + s.nonce + "," + serverFirst + "," + withoutProof
+	proof := scramHMAC(storedKey[:], s.authMessage)
+	for i := range proof {
+		proof[i] ^= clientKey[i]
+	}
+	s.serverSignature = scramHMAC(scramHMAC(salted, "Server Key"), s.authMessage)
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verify checks the server-final-message, proving that the server knows the password too.
+func (s *scramClient) verify(serverFinal string) bool {
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(serverFinal, "v="))
+	return err == nil && s.serverSignature != nil && hmac.Equal(signature, s.serverSignature)
+}
+
+func scramHMAC(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
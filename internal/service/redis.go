@@ -0,0 +1,143 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	urllib "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisService sends PING to redis://[user@]host:6379[/db], the reply must be PONG.
+// The password is read from the environment or a file, see credentials.
+type RedisService struct {
+	dialer  *net.Dialer
+	timeout time.Duration
+}
+
+// redisError is an error reply of the server, eg. "WRONGPASS invalid username-password pair".
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+func NewRedisService(settings model.AppSettings) *RedisService {
+	return &RedisService{
+		dialer:  &net.Dialer{Timeout: settings.Timeout},
+		timeout: settings.Timeout,
+	}
+}
+
+func (R RedisService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", redactURL(url)))
+	start := time.Now()
+	err := R.ping(url)
+	latency := time.Since(start)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, latency), err
+	}
+	internal.LOGGER.Info(fmt.Sprintf("%s -> PONG: %s\n", redactURL(url), latency.String()))
+	return model.NewSuccessfulResult(latency, 0), nil
+}
+
+func (R RedisService) ping(url string) error {
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return err
+	}
+	user, password, err := credentials(target)
+	if err != nil {
+		return err
+	}
+	conn, err := dialDataStore(R.dialer, target, "6379", R.timeout)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+
+	if password != "" {
+		command := []string{"AUTH", password}
+		if user != "" {
+			command = []string{"AUTH", user, password}
+		}
+		if _, err := redisCall(conn, reader, command...); err != nil {
+			return redisFailure(err)
+		}
+	}
+	if db := strings.Trim(target.Path, "/"); db != "" && db != "0" {
+		if _, err := redisCall(conn, reader, "SELECT", db); err != nil {
+			return redisFailure(err)
+		}
+	}
+	reply, err := redisCall(conn, reader, "PING")
+	if err != nil {
+		return redisFailure(err)
+	}
+	if reply != "PONG" {
+		return failure(FailureProtocol, "expected PONG, got %q", reply)
+	}
+	_, _ = redisCall(conn, reader, "QUIT")
+	return nil
+}
+
+// redisCall sends the command and reads a simple, integer or bulk string reply.
+func redisCall(conn net.Conn, reader *bufio.Reader, args ...string) (string, error) {
+	var command strings.Builder
+	command.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		command.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+	if _, err := io.WriteString(conn, command.String()); err != nil {
+		return "", err
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return "", errors.New("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return "", nil
+		}
+		bulk := make([]byte, length+2)
+		if _, err := io.ReadFull(reader, bulk); err != nil {
+			return "", err
+		}
+		return string(bulk[:length]), nil
+	default:
+		return "", fmt.Errorf("unexpected reply %q", line)
+	}
+}
+
+// redisFailure classifies the error of a command.
+func redisFailure(err error) error {
+	var reply redisError
+	if !errors.As(err, &reply) {
+		return ioFailure(err)
+	}
+	switch message := string(reply); {
+	case strings.HasPrefix(message, "NOAUTH"), strings.HasPrefix(message, "WRONGPASS"),
+		strings.HasPrefix(message, "NOPERM"), strings.Contains(message, "password"):
+		return &CheckError{Kind: FailureAuth, Err: reply}
+	default:
+		return &CheckError{Kind: FailureQuery, Err: reply}
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"GoHealthChecker/internal/model"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Registry passes every check to the service of the URL scheme, eg. tcp:// URLs to TCPService.
+// Library users register their own services with WithScheme, for new schemes or to replace the built-in ones.
+type Registry struct {
+	services map[string]Service
+}
+
+// NewRegistry creates the registry checking http://, https:// and unix:// (HTTP over a Unix domain socket) URLs with httpService.
+func NewRegistry(httpService Service) *Registry {
+	return &Registry{services: map[string]Service{"http": httpService, "https": httpService, "unix": httpService}}
+}
+
+// NewDefaultRegistry creates the registry with the built-in services of every supported scheme.
+func NewDefaultRegistry(settings model.AppSettings) *Registry {
+	webSocketService := NewWebSocketService(settings)
+	mailService := NewMailService(settings)
+	return NewRegistry(NewHTTPService(settings)).
+		WithScheme("tcp", NewTCPService(settings)).
+		WithScheme("udp", NewUDPService(settings)).
+		WithScheme("dns", NewDNSService(settings)).
+		WithScheme("grpc", NewGRPCService(settings)).
+		WithScheme("ws", webSocketService).
+		WithScheme("wss", webSocketService).
+		WithScheme("redis", NewRedisService(settings)).
+		WithScheme("postgres", NewPostgresService(settings)).
+		WithScheme("mysql", NewMySQLService(settings)).
+		WithScheme("exec", NewExecService(settings)).
+		WithScheme("smtp", mailService).
+		WithScheme("smtps", mailService).
+		WithScheme("imap", mailService).
+		WithScheme("imaps", mailService).
+		WithScheme("ftp", mailService)
+}
+
+// WithScheme registers the service checking the URLs of the scheme, replacing the previous one.
+func (r *Registry) WithScheme(scheme string, service Service) *Registry {
+	r.services[strings.ToLower(scheme)] = service
+	return r
+}
+
+// Schemes returns the registered schemes, sorted.
+func (r *Registry) Schemes() []string {
+	schemes := make([]string, 0, len(r.services))
+	for scheme := range r.services {
+		schemes = append(schemes, scheme)
+	}
+	slices.Sort(schemes)
+	return schemes
+}
+
+// ValidateURL checks that a service is registered for the scheme of the URL and that the service accepts it.
+// Services which don't implement Validator accept the URLs with a valid host.
+func (r *Registry) ValidateURL(url string) error {
+	service, err := r.lookup(url)
+	if err != nil {
+		return err
+	}
+	if validator, ok := service.(Validator); ok {
+		return validator.ValidateURL(url)
+	}
+	return ValidateURL(url)
+}
+
+// Scheme returns the lowercase scheme of url, which is all that the URLs of every scheme have in common,
+// eg. exec:// URLs are command lines.
+func Scheme(url string) (string, error) {
+	scheme, rest, found := strings.Cut(url, "://")
+	if !found || scheme == "" || rest == "" {
+		return "", errors.New("invalid URL: expected scheme://, eg. https://example.com")
+	}
+	return strings.ToLower(scheme), nil
+}
+
+func (r *Registry) CheckUrl(url string) (model.HealthCheckResult, error) {
+	service, err := r.lookup(url)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	return service.CheckUrl(url)
+}
+
+func (r *Registry) lookup(url string) (Service, error) {
+	scheme, err := Scheme(url)
+	if err != nil {
+		return nil, err
+	}
+	service, ok := r.services[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported URL scheme: %s", scheme)
+	}
+	return service, nil
+}
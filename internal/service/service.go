@@ -3,9 +3,14 @@ package service
 import (
 	"GoHealthChecker/internal"
 	"GoHealthChecker/internal/model"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -50,8 +55,16 @@ func NewHTTPServiceWithTransport(transport http.RoundTripper, settings model.App
 
 func (H HTTPService) CheckUrl(url string) (model.HealthCheckResult, error) {
 	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", url))
+	client, target := H.client, url
+	if strings.HasPrefix(url, "unix://") {
+		socket, requestURL, err := ParseUnixURL(url)
+		if err != nil {
+			return model.NewHealthCheckResultWithError(err, 0), err
+		}
+		client, target = H.unixClient(socket), requestURL
+	}
 	start := time.Now()
-	resp, err := H.client.Get(url)
+	resp, err := client.Get(target)
 	duration := time.Since(start)
 
 	if err != nil {
@@ -64,5 +77,49 @@ func (H HTTPService) CheckUrl(url string) (model.HealthCheckResult, error) {
 		return model.NewHealthCheckResultWithError(err, duration), err
 	}
 	var sizeOfResponse = uint64(len(data))
-	return model.NewHealthCheckResult(resp.StatusCode, duration, sizeOfResponse), nil
+	result := model.NewHealthCheckResult(resp.StatusCode, duration, sizeOfResponse)
+	result.CertExpiry = certificateExpiry(resp.TLS)
+	return result, nil
+}
+
+// ParseUnixURL splits unix:///var/run/app.sock:/healthz into the path of the socket
+// and the URL of the request sent over it, http://localhost/healthz.
+func ParseUnixURL(url string) (string, string, error) {
+	rest := strings.TrimPrefix(url, "unix://")
+	socket, path := rest, "/"
+	if i := strings.Index(rest, ":/"); i >= 0 {
+		socket, path = rest[:i], rest[i+1:]
+	}
+	if !strings.HasPrefix(socket, "/") || strings.ContainsAny(socket, "?#") {
+		return "", "", errors.New("unix URL must have the absolute path of the socket, eg. unix:///var/run/app.sock:/healthz")
+	}
+	return socket, "http://localhost" + path, nil
+}
+
+// unixClient returns a copy of the client connecting to the Unix domain socket instead of the host of the URL.
+func (H HTTPService) unixClient(socket string) *http.Client {
+	client := *H.client
+	dialer := &net.Dialer{}
+	client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+		// The transport lives for a single check
+		DisableKeepAlives: true,
+	}
+	return &client
+}
+
+// certificateExpiry returns the earliest expiry of the certificates presented by the server.
+func certificateExpiry(state *tls.ConnectionState) time.Time {
+	if state == nil {
+		return time.Time{}
+	}
+	var expiry time.Time
+	for _, certificate := range state.PeerCertificates {
+		if expiry.IsZero() || certificate.NotAfter.Before(expiry) {
+			expiry = certificate.NotAfter
+		}
+	}
+	return expiry
 }
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	urllib "net/url"
+	"time"
+)
+
+// maxBannerSize is how much of the response is read while looking for the expected bytes.
+const maxBannerSize = 4 * 1024
+
+// TCPService checks that a TCP port accepts connections, eg. tcp://db.internal:5432.
+// Optionally it sends the "send" query parameter and expects the response to contain
+// the "expect" parameter, eg. tcp://cache.internal:6379?send=PING%0D%0A&expect=%2BPONG.
+// The latency of the result is the time to connect.
+type TCPService struct {
+	dialer  *net.Dialer
+	timeout time.Duration
+}
+
+func NewTCPService(settings model.AppSettings) *TCPService {
+	return &TCPService{
+		dialer:  &net.Dialer{Timeout: settings.Timeout},
+		timeout: settings.Timeout,
+	}
+}
+
+func (T TCPService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", url))
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	start := time.Now()
+	conn, err := T.dialer.Dial("tcp", target.Host)
+	latency := time.Since(start)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, latency), err
+	}
+	defer func() { _ = conn.Close() }()
+	internal.LOGGER.Info(fmt.Sprintf("%s -> connected: %s\n", url, latency.String()))
+
+	query := target.Query()
+	banner, err := T.exchange(conn, []byte(query.Get("send")), []byte(query.Get("expect")))
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, latency), err
+	}
+	return model.NewSuccessfulResult(latency, uint64(len(banner))), nil
+}
+
+// exchange writes send to the connection and reads until the response contains expect.
+// It returns the response read.
+func (T TCPService) exchange(conn net.Conn, send []byte, expect []byte) ([]byte, error) {
+	if len(send) == 0 && len(expect) == 0 {
+		return nil, nil
+	}
+	if T.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(T.timeout))
+	}
+	if len(send) > 0 {
+		if _, err := conn.Write(send); err != nil {
+			return nil, fmt.Errorf("failed to send: %w", err)
+		}
+	}
+	if len(expect) == 0 {
+		return nil, nil
+	}
+
+	response := make([]byte, 0, 256)
+	buffer := make([]byte, 256)
+	for len(response) < maxBannerSize {
+		n, err := conn.Read(buffer)
+		response = append(response, buffer[:n]...)
+		if bytes.Contains(response, expect) {
+			return response, nil
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return response, fmt.Errorf("expected %q, got %q: %w", expect, response, err)
+		}
+	}
+	return response, fmt.Errorf("expected %q, got %q", expect, response)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	urllib "net/url"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+// maxDatagramSize is the largest UDP payload.
+const maxDatagramSize = 64 * 1024
+
+// UDPService sends a datagram to udp://host:port and waits for the response, the query parameters are:
+//   - send: payload of the datagram, empty by default
+//   - expect: the response must contain it
+//   - match: the response must match the regular expression
+//   - response=optional: no response within the timeout is fine, eg. for syslog collectors,
+//     only an ICMP port unreachable fails the check
+//
+// Without a response the check fails with "no response", an ICMP port unreachable fails
+// with "port unreachable". The latency of the result is the time from sending to the response.
+type UDPService struct {
+	dialer  *net.Dialer
+	timeout time.Duration
+}
+
+func NewUDPService(settings model.AppSettings) *UDPService {
+	return &UDPService{
+		dialer:  &net.Dialer{Timeout: settings.Timeout},
+		timeout: settings.Timeout,
+	}
+}
+
+func (U UDPService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", url))
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	query := target.Query()
+	var pattern *regexp.Regexp
+	if match := query.Get("match"); match != "" {
+		if pattern, err = regexp.Compile(match); err != nil {
+			return model.NewHealthCheckResultWithError(err, 0), err
+		}
+	}
+	conn, err := U.dialer.Dial("udp", target.Host)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	defer func() { _ = conn.Close() }()
+
+	start := time.Now()
+	response, err := U.exchange(conn, []byte(query.Get("send")), []byte(query.Get("expect")), pattern)
+	latency := time.Since(start)
+	if errors.Is(err, errNoResponse) && query.Get("response") == "optional" {
+		err = nil
+	}
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, latency), err
+	}
+	internal.LOGGER.Info(fmt.Sprintf("%s -> %d bytes: %s\n", url, len(response), latency.String()))
+	return model.NewSuccessfulResult(latency, uint64(len(response))), nil
+}
+
+// errNoResponse is returned when nothing came back within the timeout.
+var errNoResponse = errors.New("no response")
+
+// exchange sends the datagram and reads datagrams until one contains expect and matches pattern.
+// It returns the last response read, other datagrams fail the check once the timeout is over.
+func (U UDPService) exchange(conn net.Conn, send []byte, expect []byte, pattern *regexp.Regexp) ([]byte, error) {
+	if U.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(U.timeout))
+	}
+	if _, err := conn.Write(send); err != nil {
+		return nil, fmt.Errorf("failed to send: %w", err)
+	}
+	var response []byte
+	var mismatch error
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		n, err := conn.Read(buffer)
+		var netError net.Error
+		switch {
+		case errors.Is(err, syscall.ECONNREFUSED):
+			return response, fmt.Errorf("port unreachable: %w", err)
+		case mismatch != nil && err != nil:
+			return response, mismatch
+		case errors.As(err, &netError) && netError.Timeout():
+			return nil, fmt.Errorf("%w within %s", errNoResponse, U.timeout)
+		case err != nil:
+			return nil, err
+		}
+		response = append([]byte{}, buffer[:n]...)
+		switch {
+		case !bytes.Contains(response, expect):
+			mismatch = fmt.Errorf("expected %q, got %q", expect, response)
+		case pattern != nil && !pattern.Match(response):
+			mismatch = fmt.Errorf("expected to match %q, got %q", pattern, response)
+		default:
+			return response, nil
+		}
+	}
+}
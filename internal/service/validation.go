@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	urllib "net/url"
+	"regexp"
+	"strings"
+)
+
+// Validator is implemented by the services checking their URLs before the first check,
+// so that a typo fails at startup instead of every check.
+type Validator interface {
+	ValidateURL(url string) error
+}
+
+// hostnameRegex matches valid hostnames: letters, digits, hyphens, dots
+var hostnameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\-\.]+[a-zA-Z0-9]$`)
+
+// sensitiveMetadata are the parts of gRPC metadata keys carrying credentials, eg. authorization or x-api-key.
+var sensitiveMetadata = []string{"authorization", "cookie", "token", "secret", "password", "api-key", "apikey"}
+
+func isSensitiveMetadata(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveMetadata {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL hides the password and the credentials in gRPC metadata of url, for the logs.
+func redactURL(url string) string {
+	item, err := urllib.Parse(url)
+	if err != nil {
+		return url
+	}
+	query := item.Query()
+	if metadata, ok := query["metadata"]; ok {
+		for i, value := range metadata {
+			if key, _, found := strings.Cut(value, ":"); found && isSensitiveMetadata(key) {
+				metadata[i] = key + ":xxxxx"
+			}
+		}
+		item.RawQuery = query.Encode()
+	}
+	return item.Redacted()
+}
+
+// noInlinePassword rejects passwords in the URL, which would show up in the logs, the output,
+// the stored history and the notifications.
+func noInlinePassword(item *urllib.URL) error {
+	if _, set := item.User.Password(); set {
+		return fmt.Errorf("%s URL must not contain the password, use password_env or password_file", item.Scheme)
+	}
+	return nil
+}
+
+// ValidateURL checks that the URL is valid and has a host, the validation of the services
+// which don't implement Validator.
+func ValidateURL(url string) error {
+	_, err := parseTargetURL(url)
+	return err
+}
+
+func parseTargetURL(url string) (*urllib.URL, error) {
+	item, err := urllib.ParseRequestURI(url)
+	if err != nil {
+		return nil, errors.New("invalid URL: " + err.Error())
+	}
+	// Check host - must have a non-empty hostname
+	if item.Host == "" {
+		return nil, errors.New("URL must have a host")
+	}
+
+	// Validate hostname format
+	hostname := item.Hostname()
+	if hostname == "" {
+		return nil, errors.New("invalid hostname")
+	}
+
+	// Check for invalid characters in hostname using regex
+	if !hostnameRegex.MatchString(hostname) {
+		return nil, errors.New("hostname contains invalid characters")
+	}
+
+	// TODO: This breaks the tests
+	//_, err = net.LookupHost(hostname)
+	//if err != nil {
+	//	return nil, fmt.Errorf("DNS resolution failed: %w", err)
+	//}
+	return item, nil
+}
+
+// validateWithPort checks that the URL has a port, eg. tcp://backend.internal:5000.
+func validateWithPort(url string) (*urllib.URL, error) {
+	item, err := parseTargetURL(url)
+	if err != nil {
+		return nil, err
+	}
+	if item.Port() == "" {
+		return nil, fmt.Errorf("%s URL must have a port, eg. %s://backend.internal:5000", item.Scheme, item.Scheme)
+	}
+	return item, nil
+}
+
+// validateWithUser checks that the URL has a user without a password, eg. postgres://monitor@db.internal/app.
+func validateWithUser(url string) error {
+	item, err := parseTargetURL(url)
+	if err != nil {
+		return err
+	}
+	if item.User.Username() == "" {
+		return fmt.Errorf("%s URL must have a user, eg. %s://monitor@db.internal/app", item.Scheme, item.Scheme)
+	}
+	return noInlinePassword(item)
+}
+
+// ValidateURL accepts http://, https:// and unix:// URLs.
+func (H HTTPService) ValidateURL(url string) error {
+	if strings.HasPrefix(url, "unix://") {
+		_, _, err := ParseUnixURL(url)
+		return err
+	}
+	item, err := parseTargetURL(url)
+	if err != nil {
+		return err
+	}
+	if item.Scheme != "http" && item.Scheme != "https" {
+		return errors.New("unsupported URL scheme: " + item.Scheme)
+	}
+	return nil
+}
+
+func (T TCPService) ValidateURL(url string) error {
+	_, err := validateWithPort(url)
+	return err
+}
+
+// ValidateURL also compiles the match parameter.
+func (U UDPService) ValidateURL(url string) error {
+	item, err := validateWithPort(url)
+	if err != nil {
+		return err
+	}
+	if match := item.Query().Get("match"); match != "" {
+		if _, err := regexp.Compile(match); err != nil {
+			return fmt.Errorf("invalid match: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateURL also checks the metadata parameters, credentials must be passed in metadata_env.
+func (G GRPCService) ValidateURL(url string) error {
+	item, err := validateWithPort(url)
+	if err != nil {
+		return err
+	}
+	query := item.Query()
+	for _, metadata := range query["metadata"] {
+		key, _, found := strings.Cut(metadata, ":")
+		if !found {
+			return fmt.Errorf("invalid metadata %q, expected key:value", metadata)
+		}
+		if isSensitiveMetadata(key) {
+			return fmt.Errorf("metadata %s must not be in the URL, use metadata_env=%s:ENV_VARIABLE", key, key)
+		}
+	}
+	for _, metadata := range query["metadata_env"] {
+		if !strings.Contains(metadata, ":") {
+			return fmt.Errorf("invalid metadata_env %q, expected key:ENV_VARIABLE", metadata)
+		}
+	}
+	return nil
+}
+
+// ValidateURL also parses the query parameters, eg. the record type.
+func (D DNSService) ValidateURL(url string) error {
+	item, err := parseTargetURL(url)
+	if err != nil {
+		return err
+	}
+	if strings.Trim(item.Path, "/") == "" {
+		return errors.New("dns URL must have a name to resolve, eg. dns://10.0.0.2/api.internal")
+	}
+	_, err = parseDNSCheck(url)
+	return err
+}
+
+// ValidateURL rejects passwords in the URL.
+func (R RedisService) ValidateURL(url string) error {
+	item, err := parseTargetURL(url)
+	if err != nil {
+		return err
+	}
+	return noInlinePassword(item)
+}
+
+func (P PostgresService) ValidateURL(url string) error {
+	return validateWithUser(url)
+}
+
+func (M MySQLService) ValidateURL(url string) error {
+	return validateWithUser(url)
+}
+
+// ValidateURL parses the command, which is no URL, eg. the % of exec:///usr/lib/nagios/plugins/check_disk -w 10%.
+func (E ExecService) ValidateURL(url string) error {
+	_, err := ParseExecURL(url)
+	return err
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	urllib "net/url"
+	"time"
+)
+
+// websocketGUID is appended to the key of the handshake to compute the accept header (RFC 6455).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketMessage is the largest reply read while waiting for the expected one.
+const maxWebSocketMessage = 1 << 20
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// WebSocketService performs the upgrade handshake of ws:// and wss:// targets, eg. wss://rt.example.com/feed.
+// The reserved query parameters are not sent to the server:
+//   - send: text message sent after the handshake
+//   - expect: the reply must contain it, the sent message itself by default (echo)
+//   - insecure_skip_verify=true: don't verify the certificate of wss:// targets
+//
+// The latency of the result is the handshake, RoundTrip the time from sending the message to the reply.
+type WebSocketService struct {
+	dialer  *net.Dialer
+	timeout time.Duration
+}
+
+func NewWebSocketService(settings model.AppSettings) *WebSocketService {
+	return &WebSocketService{
+		dialer:  &net.Dialer{Timeout: settings.Timeout},
+		timeout: settings.Timeout,
+	}
+}
+
+func (W WebSocketService) CheckUrl(url string) (model.HealthCheckResult, error) {
+	internal.LOGGER.Info(fmt.Sprintf("Checking %s\n", url))
+	target, err := urllib.Parse(url)
+	if err != nil {
+		return model.NewHealthCheckResultWithError(err, 0), err
+	}
+	query := target.Query()
+	send, expect := query.Get("send"), query.Get("expect")
+	if expect == "" {
+		expect = send
+	}
+	insecure := query.Get("insecure_skip_verify") == "true"
+	for _, reserved := range []string{"send", "expect", "insecure_skip_verify"} {
+		query.Del(reserved)
+	}
+	target.RawQuery = query.Encode()
+
+	start := time.Now()
+	conn, reader, statusCode, err := W.handshake(target, insecure)
+	latency := time.Since(start)
+	if err != nil {
+		result := model.NewHealthCheckResultWithError(err, latency)
+		result.StatusCode = statusCode
+		return result, err
+	}
+	defer func() { _ = conn.Close() }()
+	internal.LOGGER.Info(fmt.Sprintf("%s -> upgraded: %s\n", url, latency.String()))
+
+	result := model.NewSuccessfulResult(latency, 0)
+	result.StatusCode = statusCode
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		result.CertExpiry = certificateExpiry(&state)
+	}
+	if expect != "" {
+		exchangeStart := time.Now()
+		reply, err := W.exchange(conn, reader, send, expect)
+		result.RoundTrip = time.Since(exchangeStart)
+		result.Size = uint64(len(reply))
+		if err != nil {
+			failed := model.NewHealthCheckResultWithError(err, latency)
+			failed.StatusCode, failed.RoundTrip, failed.CertExpiry = result.StatusCode, result.RoundTrip, result.CertExpiry
+			return failed, err
+		}
+	}
+	// Say goodbye, the server may be gone already
+	_ = writeFrame(conn, opClose, binary.BigEndian.AppendUint16(nil, 1000))
+	return result, nil
+}
+
+// handshake connects to the target and upgrades the connection, it returns the HTTP status of the upgrade.
+func (W WebSocketService) handshake(target *urllib.URL, insecure bool) (net.Conn, *bufio.Reader, int, error) {
+	address := target.Host
+	if target.Port() == "" {
+		port := "80"
+		if target.Scheme == "wss" {
+			port = "443"
+		}
+		address = net.JoinHostPort(target.Hostname(), port)
+	}
+	var conn net.Conn
+	var err error
+	if target.Scheme == "wss" {
+		conn, err = tls.DialWithDialer(W.dialer, "tcp", address, &tls.Config{ServerName: target.Hostname(), InsecureSkipVerify: insecure})
+	} else {
+		conn, err = W.dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if W.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(W.timeout))
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+	requestURI := target.RequestURI()
+	request := fmt.Sprintf("GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\nUser-Agent: GoHealthChecker\r\n\r\n", requestURI, target.Host, key)
+	if _, err := io.WriteString(conn, request); err != nil {
+		_ = conn.Close()
+		return nil, nil, 0, err
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, 0, fmt.Errorf("handshake failed: %w", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		_ = conn.Close()
+		return nil, nil, response.StatusCode, fmt.Errorf("handshake failed: HTTP %d", response.StatusCode)
+	}
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	if response.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(accept[:]) {
+		_ = conn.Close()
+		return nil, nil, response.StatusCode, errors.New("handshake failed: invalid Sec-WebSocket-Accept")
+	}
+	return conn, reader, response.StatusCode, nil
+}
+
+// exchange sends the message and reads messages until one contains expect, it returns the last message read.
+func (W WebSocketService) exchange(conn net.Conn, reader *bufio.Reader, send string, expect string) ([]byte, error) {
+	if send != "" {
+		if err := writeFrame(conn, opText, []byte(send)); err != nil {
+			return nil, fmt.Errorf("failed to send: %w", err)
+		}
+	}
+	var message []byte
+	for {
+		fin, opcode, payload, err := readFrame(reader)
+		if err != nil {
+			return message, fmt.Errorf("expected %q: %w", expect, err)
+		}
+		switch opcode {
+		case opPing:
+			_ = writeFrame(conn, opPong, payload)
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := 1005 // No status received
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			return message, fmt.Errorf("expected %q, connection closed by the server with code %d", expect, code)
+		case opText, opBinary:
+			message = payload
+		case opContinuation:
+			message = append(message, payload...)
+		}
+		if len(message) > maxWebSocketMessage {
+			return message[:maxWebSocketMessage], fmt.Errorf("expected %q, reply too large", expect)
+		}
+		if fin && bytes.Contains(message, []byte(expect)) {
+			return message, nil
+		}
+	}
+}
+
+// writeFrame writes a single masked frame, as clients must.
+func writeFrame(conn net.Conn, opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xFFFF:
+		frame = binary.BigEndian.AppendUint16(append(frame, 0x80|126), uint16(length))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 0x80|127), uint64(length))
+	}
+	mask := make([]byte, 4)
+	_, _ = rand.Read(mask)
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := conn.Write(frame)
+	return err
+}
+
+// readFrame reads a frame and returns whether it is the final one of the message, its opcode and payload.
+func readFrame(reader *bufio.Reader) (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode := header[0]&0x80 != 0, header[0]&0x0F
+	masked, length := header[1]&0x80 != 0, uint64(header[1]&0x7F)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(reader, extended); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+	}
+	if length > maxWebSocketMessage {
+		return false, 0, nil, fmt.Errorf("frame of %d bytes is too large", length)
+	}
+	mask := make([]byte, 4)
+	if masked {
+		if _, err := io.ReadFull(reader, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
@@ -0,0 +1,116 @@
+package state
+
+import (
+	"GoHealthChecker/internal/model"
+	"sync"
+	"time"
+)
+
+// FlapChange describes a target which started or stopped flapping.
+type FlapChange struct {
+	URL       string
+	Flapping  bool          // true when the target started flapping, false when it's stable again
+	Changes   int           // State changes within Window
+	Window    time.Duration // Window of the flap detection
+	State     model.State   // Current effective state
+	Timestamp time.Time
+	// Notified is the last state notified before the flapping started and Since when it was entered.
+	// A stable target in another state needs a notification about the missed change.
+	Notified model.State
+	Since    time.Time
+}
+
+type flapState struct {
+	changes       []time.Time // Timestamps of state changes within the window, oldest first
+	flapping      bool
+	notified      model.State
+	notifiedSince time.Time
+}
+
+// FlapDetector marks targets as flapping when their effective state changes more than
+// FlapThreshold times within FlapWindow. Flapping stops once the number of changes within
+// the window drops to half of the threshold, so the target doesn't bounce in and out of it.
+type FlapDetector struct {
+	mu        sync.Mutex
+	targets   map[string]*flapState
+	window    time.Duration
+	threshold int
+}
+
+func NewFlapDetector(settings model.AppSettings) *FlapDetector {
+	return &FlapDetector{
+		targets:   make(map[string]*flapState),
+		window:    settings.FlapWindow,
+		threshold: settings.FlapThreshold,
+	}
+}
+
+// Observe feeds the result of url with its state transition (nil if the state didn't change)
+// into the detector and returns the result with Flapping set. The change is returned if the
+// target started or stopped flapping.
+func (d *FlapDetector) Observe(url string, result model.HealthCheckResult, transition *Transition) (model.HealthCheckResult, *FlapChange) {
+	if d.threshold <= 0 || d.window <= 0 {
+		return result, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	target, exists := d.targets[url]
+	if !exists {
+		target = &flapState{}
+		d.targets[url] = target
+	}
+
+	if transition != nil && !transition.First {
+		target.changes = append(target.changes, transition.Timestamp)
+	}
+	since := result.Timestamp.Add(-d.window)
+	for len(target.changes) > 0 && target.changes[0].Before(since) {
+		target.changes = target.changes[1:]
+	}
+
+	var change *FlapChange
+	switch {
+	case !target.flapping && len(target.changes) > d.threshold:
+		target.flapping = true
+		change = d.newChange(url, target, result)
+	case target.flapping && len(target.changes) <= d.threshold/2:
+		target.flapping = false
+		change = d.newChange(url, target, result)
+	}
+
+	// The state notified last, transitions while flapping are not notified
+	if !target.flapping && (change != nil || transition != nil) {
+		target.notified = result.State
+		target.notifiedSince = result.Timestamp
+		if transition != nil {
+			target.notifiedSince = transition.Timestamp
+		}
+	}
+	result.Flapping = target.flapping
+	return result, change
+}
+
+// Flapping reports whether url is currently flapping.
+func (d *FlapDetector) Flapping(url string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if target, exists := d.targets[url]; exists {
+		return target.flapping
+	}
+	return false
+}
+
+func (d *FlapDetector) newChange(url string, target *flapState, result model.HealthCheckResult) *FlapChange {
+	return &FlapChange{
+		URL:       url,
+		Flapping:  target.flapping,
+		Changes:   len(target.changes),
+		Window:    d.window,
+		State:     result.State,
+		Timestamp: result.Timestamp,
+		Notified:  target.notified,
+		Since:     target.notifiedSince,
+	}
+}
@@ -0,0 +1,150 @@
+// Package state
+//
+// Tracker computes the effective state of each target from the raw check results.
+// Like HAProxy, a target becomes DOWN only after Fall consecutive failures and
+// UP again only after Rise consecutive successes. The very first result sets
+// the state directly, so targets don't start as UNKNOWN.
+//
+// Checks telling more than UP or DOWN, eg. Nagios plugins, set the CheckState of the result:
+// DEGRADED (passing with a warning) and UNKNOWN (the check couldn't tell). Ordered
+// UP < DEGRADED < UNKNOWN < DOWN, worse states need Fall consecutive results and better
+// states Rise consecutive results, the state becomes the one of the last result.
+
+package state
+
+import (
+	"GoHealthChecker/internal/model"
+	"sync"
+	"time"
+)
+
+// Transition describes a change of the effective state of a target.
+type Transition struct {
+	URL       string
+	From      model.State
+	To        model.State
+	Result    model.HealthCheckResult // Result which caused the transition
+	Timestamp time.Time
+	Duration  time.Duration // Time spent in the previous state, zero for the first state
+	First     bool          // First state of the target, From is UNKNOWN
+}
+
+type targetState struct {
+	state       model.State
+	checked     bool // the target has a state from its results
+	worse       int  // consecutive results worse than the state
+	better      int  // consecutive results better than the state
+	stateChange time.Time
+}
+
+// severity orders the states from UP to DOWN.
+var severity = map[model.State]int{
+	model.StateUp:       0,
+	model.StateDegraded: 1,
+	model.StateUnknown:  2,
+	model.StateDown:     3,
+}
+
+// outcome is the state the result alone tells.
+func outcome(result model.HealthCheckResult) model.State {
+	switch {
+	case result.CheckState == model.StateUnknown:
+		return model.StateUnknown
+	case result.IsOk && result.CheckState == model.StateDegraded:
+		return model.StateDegraded
+	case result.IsOk:
+		return model.StateUp
+	default:
+		return model.StateDown
+	}
+}
+
+type Tracker struct {
+	mu       sync.Mutex
+	targets  map[string]*targetState
+	settings model.AppSettings
+}
+
+func NewTracker(settings model.AppSettings) *Tracker {
+	return &Tracker{
+		targets:  make(map[string]*targetState),
+		settings: settings,
+	}
+}
+
+// Observe feeds a raw result of url into the tracker and returns the result with
+// the effective State set. The transition is returned if the state changed.
+func (t *Tracker) Observe(url string, result model.HealthCheckResult) (model.HealthCheckResult, *Transition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	target, exists := t.targets[url]
+	if !exists {
+		target = &targetState{state: model.StateUnknown}
+		t.targets[url] = target
+	}
+
+	observed := outcome(result)
+	switch {
+	case severity[observed] > severity[target.state]:
+		target.worse++
+		target.better = 0
+	case severity[observed] < severity[target.state]:
+		target.better++
+		target.worse = 0
+	default:
+		target.worse, target.better = 0, 0
+	}
+
+	thresholds := t.settings.TargetSettings(url)
+	next := target.state
+	if !target.checked || target.worse >= thresholds.Fall || target.better >= thresholds.Rise {
+		next = observed
+	}
+
+	result.State = next
+	var transition *Transition
+	if next != target.state {
+		transition = &Transition{
+			URL:       url,
+			From:      target.state,
+			To:        next,
+			Result:    result,
+			Timestamp: result.Timestamp,
+			First:     !target.checked,
+		}
+		if target.checked {
+			transition.Duration = result.Timestamp.Sub(target.stateChange)
+		}
+		target.state = next
+		target.stateChange = result.Timestamp
+		target.worse, target.better = 0, 0
+	}
+	if !target.checked {
+		target.stateChange = result.Timestamp
+		target.checked = true
+	}
+	return result, transition
+}
+
+// State returns the current effective state of url.
+func (t *Tracker) State(url string) model.State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if target, exists := t.targets[url]; exists {
+		return target.state
+	}
+	return model.StateUnknown
+}
+
+// Since returns when the effective state of url last changed.
+func (t *Tracker) Since(url string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if target, exists := t.targets[url]; exists {
+		return target.stateChange
+	}
+	return time.Time{}
+}
@@ -0,0 +1,293 @@
+package store
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentPrefix     = "results-"
+	segmentSuffix     = ".jsonl"
+	segmentDateLayout = "2006-01-02"
+)
+
+// FileStore is a durable Store. Every result is appended as one JSON line to a
+// daily segment file in dir, and the in-memory state (latest results, metrics)
+// is rebuilt by replaying the segments on startup. Old segments are downsampled
+// in the background according to the RetentionPolicy.
+type FileStore struct {
+	*InMemoryStore
+
+	dir string
+
+	fileMu     sync.Mutex
+	file       *os.File
+	segmentDay string
+
+	retention      RetentionPolicy
+	compactionMu   sync.Mutex
+	compactionWg   sync.WaitGroup
+	stopCompaction chan struct{}
+	closeOnce      sync.Once
+}
+
+// resultRecord is the on-disk representation of a single HealthCheckResult.
+// The error is stored as its message, since error values can't be marshalled.
+type resultRecord struct {
+	URL string `json:"url"`
+	model.HealthCheckResult
+	ErrorMessage string `json:"error,omitempty"`
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	return NewFileStoreWithOptions(dir, DefaultOptions())
+}
+
+func NewFileStoreWithOptions(dir string, options Options) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory %s: %w", dir, err)
+	}
+	s := &FileStore{
+		InMemoryStore:  NewInMemoryStoreWithHistory(options.History),
+		dir:            dir,
+		retention:      options.Retention,
+		stopCompaction: make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if s.retention.enabled() {
+		s.compactionWg.Add(1)
+		go s.runCompaction()
+	}
+	return s, nil
+}
+
+func (s *FileStore) SaveResult(url string, result model.HealthCheckResult) {
+	s.InMemoryStore.SaveResult(url, result)
+
+	if err := s.append(url, result); err != nil {
+		internal.LOGGER.Error(fmt.Sprintf("Failed to persist result for %s: %s", url, err))
+	}
+}
+
+// GetHistory serves the range from memory when the in-memory history covers it,
+// otherwise it scans the segments on disk.
+func (s *FileStore) GetHistory(url string, since, until time.Time) []model.HealthCheckResult {
+	if oldest, ok := s.oldestInHistory(url); ok && !since.IsZero() && !since.Before(oldest) {
+		return s.InMemoryStore.GetHistory(url, since, until)
+	}
+
+	results, err := s.readHistory(url, since, until)
+	if err != nil {
+		internal.LOGGER.Error(fmt.Sprintf("Failed to read history for %s: %s", url, err))
+		return s.InMemoryStore.GetHistory(url, since, until)
+	}
+	return results
+}
+
+func (s *FileStore) Close() error {
+	s.closeOnce.Do(func() { close(s.stopCompaction) })
+	s.compactionWg.Wait()
+
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *FileStore) append(url string, result model.HealthCheckResult) error {
+	record := resultRecord{URL: url, HealthCheckResult: result}
+	if result.Error != nil {
+		record.ErrorMessage = result.Error.Error()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+
+	file, err := s.segmentFor(result.Timestamp)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(line)
+	return err
+}
+
+// segmentFor returns the segment file for the day of timestamp, rotating the
+// currently open segment if the day changed. Caller must hold fileMu.
+func (s *FileStore) segmentFor(timestamp time.Time) (*os.File, error) {
+	day := timestamp.UTC().Format(segmentDateLayout)
+	if s.file != nil && s.segmentDay == day {
+		return s.file, nil
+	}
+	if s.file != nil {
+		_ = s.file.Close()
+		s.file = nil
+	}
+
+	path := filepath.Join(s.dir, segmentPrefix+day+segmentSuffix)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+	s.segmentDay = day
+	return file, nil
+}
+
+// load rebuilds the in-memory state, first from the hour rollups of compacted
+// days and then by replaying the raw results in chronological order: the batches
+// of an interrupted compaction which weren't merged yet, then the raw segments.
+func (s *FileStore) load() error {
+	mergedBatches, err := s.loadRollups()
+	if err != nil {
+		return err
+	}
+	batches, err := s.listSegments(compactingPrefix)
+	if err != nil {
+		return err
+	}
+	segments, err := s.listSegments(segmentPrefix)
+	if err != nil {
+		return err
+	}
+	for _, path := range append(batches, segments...) {
+		if mergedBatches[batchName(path)] {
+			continue
+		}
+		if err := s.replay(path); err != nil {
+			return fmt.Errorf("failed to load segment %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) replay(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		url, result, err := decodeRecord(scanner.Bytes())
+		if err != nil {
+			// A partially written line is expected after a crash, skip it
+			internal.LOGGER.Warn(fmt.Sprintf("Skipping corrupted record %s:%d: %s", path, lineNumber, err))
+			continue
+		}
+		s.InMemoryStore.SaveResult(url, result)
+	}
+	return scanner.Err()
+}
+
+func (s *FileStore) readHistory(url string, since, until time.Time) ([]model.HealthCheckResult, error) {
+	segments, err := s.listSegments(segmentPrefix)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]model.HealthCheckResult, 0)
+	for _, path := range segments {
+		if !segmentOverlaps(path, segmentPrefix, since, until) {
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			recordURL, result, err := decodeRecord(scanner.Bytes())
+			if err != nil || recordURL != url || !inRange(result.Timestamp, since, until) {
+				continue
+			}
+			results = append(results, result)
+		}
+		err = scanner.Err()
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// segmentOverlaps reports whether the day covered by the segment at path intersects [since, until].
+func segmentOverlaps(path string, prefix string, since, until time.Time) bool {
+	dayStart, ok := segmentDay(path, prefix)
+	if !ok {
+		// Unknown naming, scan it to be safe
+		return true
+	}
+	dayEnd := dayStart.Add(24 * time.Hour)
+	if !since.IsZero() && !dayEnd.After(since) {
+		return false
+	}
+	if !until.IsZero() && dayStart.After(until) {
+		return false
+	}
+	return true
+}
+
+// listSegments returns paths of all segments with prefix sorted from oldest to newest.
+func (s *FileStore) listSegments(prefix string) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		paths = append(paths, filepath.Join(s.dir, name))
+	}
+	// Date layout sorts lexicographically
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func decodeRecord(data []byte) (string, model.HealthCheckResult, error) {
+	var record resultRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return "", model.HealthCheckResult{}, err
+	}
+	if record.URL == "" {
+		return "", model.HealthCheckResult{}, errors.New("record without URL")
+	}
+	result := record.HealthCheckResult
+	if record.ErrorMessage != "" {
+		result.Error = errors.New(record.ErrorMessage)
+	}
+	return record.URL, result, nil
+}
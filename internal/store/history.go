@@ -0,0 +1,92 @@
+package store
+
+import (
+	"GoHealthChecker/internal/model"
+	"time"
+)
+
+// DefaultHistorySize is the number of results kept per URL when no limit is configured.
+const DefaultHistorySize = 1000
+
+// HistoryLimit bounds the per-URL history kept in memory.
+// MaxResults caps the number of results, MaxAge (optional) additionally drops
+// results older than MaxAge relative to the newest result.
+type HistoryLimit struct {
+	MaxResults int
+	MaxAge     time.Duration
+}
+
+func DefaultHistoryLimit() HistoryLimit {
+	return HistoryLimit{MaxResults: DefaultHistorySize}
+}
+
+// resultRing is a fixed size ring buffer of results ordered from oldest to newest.
+type resultRing struct {
+	buffer []model.HealthCheckResult
+	head   int // index of the oldest result
+	size   int
+	maxAge time.Duration
+}
+
+func newResultRing(limit HistoryLimit) *resultRing {
+	capacity := limit.MaxResults
+	if capacity <= 0 {
+		capacity = DefaultHistorySize
+	}
+	return &resultRing{
+		buffer: make([]model.HealthCheckResult, capacity),
+		maxAge: limit.MaxAge,
+	}
+}
+
+func (r *resultRing) add(result model.HealthCheckResult) {
+	if r.size == len(r.buffer) {
+		// Full, overwrite the oldest result
+		r.buffer[r.head] = result
+		r.head = (r.head + 1) % len(r.buffer)
+	} else {
+		r.buffer[(r.head+r.size)%len(r.buffer)] = result
+		r.size++
+	}
+
+	if r.maxAge <= 0 {
+		return
+	}
+	cutoff := result.Timestamp.Add(-r.maxAge)
+	for r.size > 0 && r.buffer[r.head].Timestamp.Before(cutoff) {
+		r.buffer[r.head] = model.HealthCheckResult{}
+		r.head = (r.head + 1) % len(r.buffer)
+		r.size--
+	}
+}
+
+// oldest returns the timestamp of the oldest result still in the ring.
+func (r *resultRing) oldest() (time.Time, bool) {
+	if r.size == 0 {
+		return time.Time{}, false
+	}
+	return r.buffer[r.head].Timestamp, true
+}
+
+// between returns copies of results with since <= Timestamp <= until.
+// Zero since or until leaves that side of the range open.
+func (r *resultRing) between(since, until time.Time) []model.HealthCheckResult {
+	results := make([]model.HealthCheckResult, 0, r.size)
+	for i := 0; i < r.size; i++ {
+		result := r.buffer[(r.head+i)%len(r.buffer)]
+		if inRange(result.Timestamp, since, until) {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func inRange(timestamp, since, until time.Time) bool {
+	if !since.IsZero() && timestamp.Before(since) {
+		return false
+	}
+	if !until.IsZero() && timestamp.After(until) {
+		return false
+	}
+	return true
+}
@@ -3,20 +3,31 @@ package store
 import (
 	"GoHealthChecker/internal/model"
 	"sync"
+	"time"
 )
 
 type InMemoryStore struct {
 	mu            sync.RWMutex
 	latestResults map[string]model.HealthCheckResult
 	resultMetrics map[string]model.Metrics
+	history       map[string]*resultRing
+	historyLimit  HistoryLimit
+	incidents     map[string]*model.IncidentTracker
 
 	registeredURLs []string
 }
 
 func NewInMemoryStore() *InMemoryStore {
+	return NewInMemoryStoreWithHistory(DefaultHistoryLimit())
+}
+
+func NewInMemoryStoreWithHistory(limit HistoryLimit) *InMemoryStore {
 	return &InMemoryStore{
 		latestResults:  make(map[string]model.HealthCheckResult),
 		resultMetrics:  make(map[string]model.Metrics),
+		history:        make(map[string]*resultRing),
+		incidents:      make(map[string]*model.IncidentTracker),
+		historyLimit:   limit,
 		registeredURLs: make([]string, 0),
 	}
 }
@@ -37,11 +48,34 @@ func (s *InMemoryStore) SaveResult(url string, result model.HealthCheckResult) {
 		metrics.Update(result)
 		s.resultMetrics[url] = metrics
 	}
+
+	if _, exists := s.history[url]; !exists {
+		s.history[url] = newResultRing(s.historyLimit)
+	}
+	s.history[url].add(result)
+
+	if _, exists := s.incidents[url]; !exists {
+		s.incidents[url] = model.NewIncidentTracker()
+	}
+	s.incidents[url].Observe(result)
+}
+
+// saveRollup folds a downsampled bucket into the metrics without touching
+// the latest result or the history.
+func (s *InMemoryStore) saveRollup(url string, bucket model.RollupBucket) {
+	if bucket.Count == 0 && bucket.Maintenance == 0 && bucket.Unknown == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics := s.resultMetrics[url]
+	metrics.Merge(bucket)
+	s.resultMetrics[url] = metrics
 }
 
 func (s *InMemoryStore) AddURL(url string) error {
-	err := ValidateURL(url)
-	if err != nil {
+	if err := validateScheme(url); err != nil {
 		return err
 	}
 	s.mu.Lock()
@@ -79,7 +113,48 @@ func (s *InMemoryStore) GetMetrics() map[string]model.Metrics {
 
 	metrics := make(map[string]model.Metrics, len(s.resultMetrics))
 	for k, v := range s.resultMetrics {
+		if tracker, exists := s.incidents[k]; exists {
+			tracker.Summarize(&v)
+		}
 		metrics[k] = v
 	}
 	return metrics
 }
+
+func (s *InMemoryStore) GetIncidents(url string) []model.Incident {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tracker, exists := s.incidents[url]
+	if !exists {
+		return []model.Incident{}
+	}
+	return tracker.Incidents()
+}
+
+func (s *InMemoryStore) GetHistory(url string, since, until time.Time) []model.HealthCheckResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ring, exists := s.history[url]
+	if !exists {
+		return []model.HealthCheckResult{}
+	}
+	return ring.between(since, until)
+}
+
+// oldestInHistory returns the timestamp of the oldest result kept in memory for url.
+func (s *InMemoryStore) oldestInHistory(url string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ring, exists := s.history[url]
+	if !exists {
+		return time.Time{}, false
+	}
+	return ring.oldest()
+}
+
+func (s *InMemoryStore) Close() error {
+	return nil
+}
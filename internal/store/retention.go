@@ -0,0 +1,391 @@
+package store
+
+import (
+	"GoHealthChecker/internal"
+	"GoHealthChecker/internal/model"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	minuteRollupPrefix = "rollup-1m-"
+	hourRollupPrefix   = "rollup-1h-"
+	compactingPrefix   = "compacting-"
+
+	DefaultCompactionInterval = time.Hour
+)
+
+// RetentionPolicy controls how long the FileStore keeps its data.
+// Raw results older than RawRetention are rolled up into per-minute and
+// per-hour buckets, which are in turn deleted after MinuteRetention and
+// HourRetention. A zero retention keeps the data forever.
+type RetentionPolicy struct {
+	RawRetention       time.Duration
+	MinuteRetention    time.Duration
+	HourRetention      time.Duration
+	CompactionInterval time.Duration
+}
+
+func (p RetentionPolicy) enabled() bool {
+	return p.RawRetention > 0 || p.MinuteRetention > 0 || p.HourRetention > 0
+}
+
+// rollupRecord is the on-disk representation of a single RollupBucket.
+type rollupRecord struct {
+	URL string `json:"url"`
+	model.RollupBucket
+}
+
+// rollupHeader is the first line of a rollup segment, listing the batches of raw results merged into it.
+type rollupHeader struct {
+	Batches []string `json:"batches"`
+}
+
+type rollupSegment struct {
+	Batches []string
+	Records []rollupRecord
+}
+
+// GetRollups returns downsampled buckets of url with the given resolution
+// (time.Minute or time.Hour) starting within [since, until], oldest first.
+func (s *FileStore) GetRollups(url string, resolution time.Duration, since, until time.Time) []model.RollupBucket {
+	prefix := minuteRollupPrefix
+	if resolution == time.Hour {
+		prefix = hourRollupPrefix
+	}
+	paths, err := s.listSegments(prefix)
+	if err != nil {
+		internal.LOGGER.Error(fmt.Sprintf("Failed to list rollups: %s", err))
+		return []model.RollupBucket{}
+	}
+
+	buckets := make([]model.RollupBucket, 0)
+	for _, path := range paths {
+		if !segmentOverlaps(path, prefix, since, until) {
+			continue
+		}
+		err := readRollups(path, func(recordURL string, bucket model.RollupBucket) {
+			if recordURL == url && inRange(bucket.Start, since, until) {
+				buckets = append(buckets, bucket)
+			}
+		})
+		if err != nil {
+			internal.LOGGER.Error(fmt.Sprintf("Failed to read rollups %s: %s", path, err))
+		}
+	}
+	return buckets
+}
+
+// runCompaction compacts the store every CompactionInterval until Close is called.
+func (s *FileStore) runCompaction() {
+	defer s.compactionWg.Done()
+
+	interval := s.retention.CompactionInterval
+	if interval <= 0 {
+		interval = DefaultCompactionInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.compact(time.Now().UTC()); err != nil {
+			internal.LOGGER.Error(fmt.Sprintf("Compaction of %s failed: %s", s.dir, err))
+		}
+		select {
+		case <-s.stopCompaction:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// compact downsamples expired raw segments and deletes expired rollups.
+// Batches left over from an interrupted compaction are merged first.
+func (s *FileStore) compact(now time.Time) error {
+	s.compactionMu.Lock()
+	defer s.compactionMu.Unlock()
+
+	batches, err := s.listSegments(compactingPrefix)
+	if err != nil {
+		return err
+	}
+	for _, path := range batches {
+		if day, ok := batchDay(path); ok {
+			if err := s.downsampleBatch(path, day); err != nil {
+				return fmt.Errorf("failed to downsample %s: %w", path, err)
+			}
+		}
+	}
+
+	if s.retention.RawRetention > 0 {
+		segments, err := s.listSegments(segmentPrefix)
+		if err != nil {
+			return err
+		}
+		for _, path := range segments {
+			day, ok := segmentDay(path, segmentPrefix)
+			if !ok || day.Add(24*time.Hour).After(now.Add(-s.retention.RawRetention)) {
+				continue
+			}
+			if err := s.downsample(path, day); err != nil {
+				return fmt.Errorf("failed to downsample %s: %w", path, err)
+			}
+			internal.LOGGER.Info(fmt.Sprintf("Compacted raw segment %s", path))
+		}
+	}
+
+	if err := s.deleteExpired(minuteRollupPrefix, s.retention.MinuteRetention, now); err != nil {
+		return err
+	}
+	return s.deleteExpired(hourRollupPrefix, s.retention.HourRetention, now)
+}
+
+// downsample renames a raw segment into a batch and merges the batch into the rollups of its day.
+// fileMu is only held for the rename, so SaveResult isn't blocked by the rollup. Late results of the day
+// start a new raw segment, which is merged by the next compaction.
+func (s *FileStore) downsample(path string, day time.Time) error {
+	batch := filepath.Join(s.dir, fmt.Sprintf("%s%s-%d%s", compactingPrefix, day.Format(segmentDateLayout), time.Now().UnixNano(), segmentSuffix))
+
+	s.fileMu.Lock()
+	if s.file != nil && s.segmentDay == day.Format(segmentDateLayout) {
+		_ = s.file.Close()
+		s.file = nil
+	}
+	err := os.Rename(path, batch)
+	s.fileMu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.downsampleBatch(batch, day)
+}
+
+// downsampleBatch merges the raw results of a batch into the minute and hour rollups and removes it.
+// The rollups record the batches merged into them, so a batch left over by a crash is merged only once.
+func (s *FileStore) downsampleBatch(path string, day time.Time) error {
+	byMinute := make(map[string]map[time.Time][]model.HealthCheckResult)
+	byHour := make(map[string]map[time.Time][]model.HealthCheckResult)
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		url, result, err := decodeRecord(scanner.Bytes())
+		if err != nil {
+			continue
+		}
+		addToBucket(byMinute, url, result.Timestamp.UTC().Truncate(time.Minute), result)
+		addToBucket(byHour, url, result.Timestamp.UTC().Truncate(time.Hour), result)
+	}
+	err = scanner.Err()
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+
+	name := batchName(path)
+	if err := s.mergeRollups(minuteRollupPrefix, day, time.Minute, name, byMinute); err != nil {
+		return err
+	}
+	if err := s.mergeRollups(hourRollupPrefix, day, time.Hour, name, byHour); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func addToBucket(buckets map[string]map[time.Time][]model.HealthCheckResult, url string, start time.Time, result model.HealthCheckResult) {
+	if _, exists := buckets[url]; !exists {
+		buckets[url] = make(map[time.Time][]model.HealthCheckResult)
+	}
+	buckets[url][start] = append(buckets[url][start], result)
+}
+
+// mergeRollups atomically merges the buckets of a batch into the rollup segment of one day,
+// unless the segment already lists the batch.
+func (s *FileStore) mergeRollups(prefix string, day time.Time, resolution time.Duration, batch string, buckets map[string]map[time.Time][]model.HealthCheckResult) error {
+	path := s.rollupPath(prefix, day)
+	segment, err := readRollupSegment(path)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(segment.Batches, batch) {
+		return nil
+	}
+
+	type bucketKey struct {
+		url   string
+		start time.Time
+	}
+	merged := make(map[bucketKey]*rollupRecord)
+	records := make([]*rollupRecord, 0)
+	for i := range segment.Records {
+		record := &segment.Records[i]
+		merged[bucketKey{record.URL, record.Start}] = record
+		records = append(records, record)
+	}
+	for url, starts := range buckets {
+		for start, results := range starts {
+			bucket := model.NewRollupBucket(start, resolution, results)
+			if record, exists := merged[bucketKey{url, start}]; exists {
+				record.Merge(bucket)
+				continue
+			}
+			records = append(records, &rollupRecord{URL: url, RollupBucket: bucket})
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].Start.Equal(records[j].Start) {
+			return records[i].Start.Before(records[j].Start)
+		}
+		return records[i].URL < records[j].URL
+	})
+
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	err = encoder.Encode(rollupHeader{Batches: append(segment.Batches, batch)})
+	for _, record := range records {
+		if err != nil {
+			break
+		}
+		err = encoder.Encode(record)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FileStore) deleteExpired(prefix string, retention time.Duration, now time.Time) error {
+	if retention <= 0 {
+		return nil
+	}
+	paths, err := s.listSegments(prefix)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		day, ok := segmentDay(path, prefix)
+		if !ok || day.Add(24*time.Hour).After(now.Add(-retention)) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		internal.LOGGER.Info(fmt.Sprintf("Deleted expired rollup %s", path))
+	}
+	return nil
+}
+
+// loadRollups folds the hour rollups into the metrics and returns the batches they include,
+// so batches left over from an interrupted compaction are replayed only if they weren't merged yet.
+func (s *FileStore) loadRollups() (map[string]bool, error) {
+	mergedBatches := make(map[string]bool)
+	paths, err := s.listSegments(hourRollupPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		segment, err := readRollupSegment(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rollup %s: %w", path, err)
+		}
+		for _, record := range segment.Records {
+			s.InMemoryStore.saveRollup(record.URL, record.RollupBucket)
+		}
+		for _, batch := range segment.Batches {
+			mergedBatches[batch] = true
+		}
+	}
+	return mergedBatches, nil
+}
+
+func (s *FileStore) rollupPath(prefix string, day time.Time) string {
+	return filepath.Join(s.dir, prefix+day.Format(segmentDateLayout)+segmentSuffix)
+}
+
+func readRollups(path string, fn func(url string, bucket model.RollupBucket)) error {
+	segment, err := readRollupSegment(path)
+	for _, record := range segment.Records {
+		fn(record.URL, record.RollupBucket)
+	}
+	return err
+}
+
+// readRollupSegment reads the header and the records of a rollup segment, a missing segment is empty.
+func readRollupSegment(path string) (rollupSegment, error) {
+	var segment rollupSegment
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return segment, nil
+		}
+		return segment, err
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record struct {
+			rollupRecord
+			rollupHeader
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		segment.Batches = append(segment.Batches, record.Batches...)
+		if record.URL != "" {
+			segment.Records = append(segment.Records, record.rollupRecord)
+		}
+	}
+	return segment, scanner.Err()
+}
+
+// batchName returns the name of a batch, eg. 2006-01-02-1136214245000000000 for compacting-2006-01-02-1136214245000000000.jsonl.
+func batchName(path string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), compactingPrefix), segmentSuffix)
+}
+
+// batchDay parses the day from the name of a batch.
+func batchDay(path string) (time.Time, bool) {
+	name := batchName(path)
+	if len(name) < len(segmentDateLayout) {
+		return time.Time{}, false
+	}
+	day, err := time.Parse(segmentDateLayout, name[:len(segmentDateLayout)])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// segmentDay parses the day from a segment file name like <prefix>2006-01-02.jsonl.
+func segmentDay(path string, prefix string) (time.Time, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), prefix), segmentSuffix)
+	day, err := time.Parse(segmentDateLayout, name)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
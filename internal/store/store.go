@@ -3,8 +3,11 @@ package store
 import (
 	"GoHealthChecker/internal/model"
 	"errors"
+	"fmt"
 	urllib "net/url"
 	"regexp"
+	"strings"
+	"time"
 )
 
 type Store interface {
@@ -14,11 +17,49 @@ type Store interface {
 	GetURLs() []string
 	GetLatestResults() map[string]model.HealthCheckResult
 	GetMetrics() map[string]model.Metrics
+	// GetHistory returns results for url with since <= Timestamp <= until, oldest first.
+	// Zero since or until leaves that side of the range open.
+	GetHistory(url string, since, until time.Time) []model.HealthCheckResult
+	// GetIncidents returns the outages detected for url, oldest first.
+	GetIncidents(url string) []model.Incident
+
+	// Close releases any resources held by the store (open files, goroutines).
+	Close() error
+}
+
+// Options configure stores created by NewStore.
+type Options struct {
+	History HistoryLimit
+	// Retention only applies to stores that persist results.
+	Retention RetentionPolicy
+}
+
+func DefaultOptions() Options {
+	return Options{History: DefaultHistoryLimit()}
+}
+
+// NewStore creates a Store from a spec given on the command line.
+// Supported specs are "memory" (default) and "file:<directory>".
+func NewStore(spec string, options Options) (Store, error) {
+	switch {
+	case spec == "" || spec == "memory":
+		return NewInMemoryStoreWithHistory(options.History), nil
+	case strings.HasPrefix(spec, "file:"):
+		dir := strings.TrimPrefix(spec, "file:")
+		if dir == "" {
+			return nil, errors.New("file store requires a directory, eg. file:/var/lib/hc")
+		}
+		return NewFileStoreWithOptions(dir, options)
+	default:
+		return nil, fmt.Errorf("unsupported store: %s", spec)
+	}
 }
 
+// ValidateURL checks that url is a valid http:// or https:// URL with a host.
+//
+// Deprecated: the stores accept URLs of every scheme, which the checkers validate before
+// the first check, see service.Registry.ValidateURL.
 func ValidateURL(url string) error {
-	// This method is not implemented in the interface, but can be used by implementations
-	// check if the URL is valid
 	item, err := urllib.ParseRequestURI(url)
 	if err != nil {
 		return errors.New("invalid URL: " + err.Error())
@@ -39,16 +80,21 @@ func ValidateURL(url string) error {
 	}
 
 	// Check for invalid characters in hostname using regex
-	// Valid hostname: letters, digits, hyphens, dots
-	hostnameRegex := regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\-\.]+[a-zA-Z0-9]$`)
 	if !hostnameRegex.MatchString(hostname) {
 		return errors.New("hostname contains invalid characters")
 	}
+	return nil
+}
+
+// Valid hostname: letters, digits, hyphens, dots
+var hostnameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\-\.]+[a-zA-Z0-9]$`)
 
-	// TODO: This breaks the tests
-	//_, err = net.LookupHost(hostname)
-	//if err != nil {
-	//	return fmt.Errorf("DNS resolution failed: %w", err)
-	//}
+// validateScheme checks that url has a scheme, all that the URLs of every scheme have in common,
+// eg. exec:// URLs are command lines.
+func validateScheme(url string) error {
+	scheme, rest, found := strings.Cut(url, "://")
+	if !found || scheme == "" || rest == "" {
+		return errors.New("invalid URL: expected scheme://, eg. https://example.com")
+	}
 	return nil
 }
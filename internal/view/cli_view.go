@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // CLIView TODO: Change current implementation to use better terminal GUI library
@@ -43,9 +44,18 @@ func (v *CLIView) Render(results map[string]model.HealthCheckResult) {
 	// Iterate through sorted URLs
 	for _, url := range urls {
 		result := results[url]
-		state := "UP"
-		if !result.IsOk {
-			state = "DOWN"
+		state := string(result.State)
+		if result.State == "" {
+			state = "UP"
+			if !result.IsOk {
+				state = "DOWN"
+			}
+		}
+		if result.Flapping {
+			state = string(model.StateFlapping)
+		}
+		if result.Maintenance {
+			state = "MAINT"
 		}
 		if result.Error == nil {
 			t.AppendRow(
@@ -86,6 +96,7 @@ func (v *CLIView) RenderMetrics(results map[string]model.Metrics) {
 		"Avg. Latency", "Avg. Size",
 		"Min Latency", "Min Size",
 		"Max Latency", "Max Size",
+		"Incidents", "MTTR", "MTBF", "Longest Outage", "Streak",
 	})
 
 	// Extract and sort the URLs
@@ -98,15 +109,16 @@ func (v *CLIView) RenderMetrics(results map[string]model.Metrics) {
 	// Iterate through sorted URLs
 	for _, url := range urls {
 		result := results[url]
-		uptime := fmt.Sprintf("%.1f%%", float64(result.SuccessRequests)/float64(result.TotalRequests)*100)
 		t.AppendRow(
 			table.Row{
 				url,
 				fmt.Sprintf("%d/%d", result.SuccessRequests, result.FailedRequests),
-				uptime,
+				formatUptime(result),
 				addSuffix(result.LatencyAverage, "ms"), formatBytes(result.SizeAverage),
 				addSuffix(result.LatencyMin, "ms"), formatBytes(result.SizeMin),
 				addSuffix(result.LatencyMax, "ms"), formatBytes(result.SizeMax),
+				result.Incidents, formatDuration(result.MTTR), formatDuration(result.MTBF),
+				formatDuration(result.LongestOutage), formatStreak(result),
 			},
 		)
 	}
@@ -124,6 +136,34 @@ func addSuffix(data float64, suffix string) string {
 	return fmt.Sprintf("%.2f%s", data, suffix)
 }
 
+func formatDuration(duration time.Duration) string {
+	if duration == 0 {
+		return "-"
+	}
+	return duration.Round(time.Second).String()
+}
+
+// formatUptime shows MAINT for targets checked only under maintenance, which don't count into the uptime.
+func formatUptime(metrics model.Metrics) string {
+	if metrics.TotalRequests == 0 {
+		if metrics.MaintenanceRequests > 0 {
+			return "MAINT"
+		}
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(metrics.SuccessRequests)/float64(metrics.TotalRequests)*100)
+}
+
+func formatStreak(metrics model.Metrics) string {
+	if metrics.CurrentStreak == 0 {
+		return "-"
+	}
+	if metrics.CurrentStreakOk {
+		return fmt.Sprintf("%d UP", metrics.CurrentStreak)
+	}
+	return fmt.Sprintf("%d DOWN", metrics.CurrentStreak)
+}
+
 func formatBytes(bytes uint64) string {
 	if bytes == 0 {
 		return "0 B"
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// SMTPMessage is an email received by the FakeSMTPServer.
+type SMTPMessage struct {
+	From     string
+	To       []string
+	Data     string
+	Username string // Set if the client authenticated
+	TLS      bool   // Whether the message was sent over an encrypted connection
+}
+
+// FakeSMTPServer is a minimal in-process SMTP server accepting every message.
+// It supports STARTTLS (or implicit TLS) and AUTH PLAIN.
+type FakeSMTPServer struct {
+	Address  string
+	Banner   string // Greeting, defaults to "fake.smtp ESMTP ready"
+	listener net.Listener
+	tls      *tls.Config
+	implicit bool
+
+	mu       sync.Mutex
+	messages []SMTPMessage
+	wg       sync.WaitGroup
+}
+
+// NewFakeSMTPServer starts the server on a random local port. With a non-nil tlsConfig
+// it offers STARTTLS, or speaks TLS right away when implicit is true.
+func NewFakeSMTPServer(tlsConfig *tls.Config, implicit bool) (*FakeSMTPServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil && implicit {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	server := &FakeSMTPServer{
+		Address:  listener.Addr().String(),
+		Banner:   "fake.smtp ESMTP ready",
+		listener: listener,
+		tls:      tlsConfig,
+		implicit: implicit,
+	}
+	server.wg.Add(1)
+	go server.serve()
+	return server, nil
+}
+
+func (s *FakeSMTPServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *FakeSMTPServer) Messages() []SMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SMTPMessage{}, s.messages...)
+}
+
+func (s *FakeSMTPServer) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *FakeSMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *FakeSMTPServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = fmt.Fprintf(conn, "%s\r\n", line)
+	}
+
+	encrypted := s.implicit
+	message := SMTPMessage{TLS: encrypted}
+	reply("220 " + s.Banner)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			if s.tls != nil && !encrypted {
+				reply("250-fake.smtp")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-fake.smtp")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+			encrypted = true
+			message = SMTPMessage{TLS: true}
+		case "AUTH":
+			parts := strings.Fields(line)
+			if len(parts) == 3 {
+				decoded, _ := base64.StdEncoding.DecodeString(parts[2])
+				fields := strings.Split(string(decoded), "\x00")
+				if len(fields) == 3 {
+					message.Username = fields[1]
+				}
+			}
+			reply("235 Authentication successful")
+		case "MAIL":
+			message.From = extractAddress(line)
+			reply("250 OK")
+		case "RCPT":
+			message.To = append(message.To, extractAddress(line))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			message.Data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			message = SMTPMessage{TLS: encrypted, Username: message.Username}
+			reply("250 OK: queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func extractAddress(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
@@ -0,0 +1,199 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/dns"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/tests"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDNSServer answers queries from its zone over UDP and TCP on the same port.
+// Answers of names in truncated are sent over UDP with the TC flag and without records.
+type fakeDNSServer struct {
+	zone      map[string][]dns.Record // By "name type", eg. "api.test. A"
+	truncated map[string]bool
+}
+
+func (s *fakeDNSServer) answer(data []byte, overTCP bool) []byte {
+	query, err := dns.Unpack(data)
+	if err != nil || len(query.Questions) != 1 {
+		return nil
+	}
+	question := query.Questions[0]
+	question.Name = strings.ToLower(question.Name) // Names are case-insensitive
+	response := dns.Message{ID: query.ID, Response: true, RecursionDesired: query.RecursionDesired, Questions: query.Questions}
+	records, found := s.zone[question.Name+" "+question.Type.String()]
+	switch {
+	case s.truncated[question.Name] && !overTCP:
+		response.Truncated = true
+	case !found && !strings.HasSuffix(question.Name, ".test."):
+		response.RCode = dns.RCodeServerFailure
+	case !found:
+		response.RCode = dns.RCodeNameError
+	default:
+		response.Answers = records
+	}
+	packed, _ := response.Pack()
+	return packed
+}
+
+// start listens on a random local port and returns its address.
+func (s *fakeDNSServer) start(t *testing.T) string {
+	var udp net.PacketConn
+	var tcp net.Listener
+	// The TCP port may be taken for UDP, try a few random ports
+	for range 10 {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		if udp, err = net.ListenPacket("udp", listener.Addr().String()); err == nil {
+			tcp = listener
+			break
+		}
+		_ = listener.Close()
+	}
+	require.NotNil(t, tcp, "no free port for both TCP and UDP")
+	t.Cleanup(func() {
+		_ = udp.Close()
+		_ = tcp.Close()
+	})
+
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, address, err := udp.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := s.answer(buffer[:n], false); response != nil {
+				_, _ = udp.WriteTo(response, address)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			length := make([]byte, 2)
+			if _, err := io.ReadFull(conn, length); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					response := s.answer(query, true)
+					_, _ = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...))
+				}
+			}
+			_ = conn.Close()
+		}
+	}()
+	return tcp.Addr().String()
+}
+
+func TestDNSServiceResolvesAndAssertsRecords(t *testing.T) {
+	t.Parallel()
+	server := &fakeDNSServer{
+		zone: map[string][]dns.Record{
+			"api.test. A": {
+				{Name: "api.test.", Type: dns.TypeCNAME, TTL: 300, Value: "lb.test."},
+				{Name: "lb.test.", Type: dns.TypeA, TTL: 60, Value: "10.0.0.1"},
+				{Name: "lb.test.", Type: dns.TypeA, TTL: 60, Value: "10.0.0.2"},
+			},
+			"api.test. AAAA": {{Name: "api.test.", Type: dns.TypeAAAA, TTL: 60, Value: "fd00::1"}},
+			"test. MX":       {{Name: "test.", Type: dns.TypeMX, TTL: 3600, Value: "10 mail.test."}},
+			"empty.test. A":  {},
+			"big.test. TXT":  {{Name: "big.test.", Type: dns.TypeTXT, TTL: 60, Value: strings.Repeat("v=spf1 ", 100)}},
+		},
+		truncated: map[string]bool{"big.test.": true},
+	}
+	resolver := server.start(t)
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	dnsService := service.NewDNSService(settings)
+
+	passing := []string{
+		"dns://" + resolver + "/api.test",
+		"dns://" + resolver + "/api.test?type=A&expect=10.0.0.2,10.0.0.1&count=2",
+		"dns://" + resolver + "/api.test?min_ttl=30s&max_ttl=5m",
+		"dns://" + resolver + "/API.test.?type=aaaa&expect=fd00::1",
+		"dns://" + resolver + "/test?type=MX&expect=10%20mail.test",
+		"dns://" + resolver + "/empty.test?count=0",
+		"dns://" + resolver + "/big.test?type=TXT",
+	}
+	for _, url := range passing {
+		result, err := dnsService.CheckUrl(url)
+		require.NoError(t, err, url)
+		assert.True(t, result.IsOk, url)
+		assert.Positive(t, result.Latency, url)
+		assert.Positive(t, result.Size, url)
+	}
+
+	failing := map[string]string{
+		"dns://" + resolver + "/api.test?expect=10.0.0.3":        "A record 10.0.0.3 not found for api.test., got: 10.0.0.1, 10.0.0.2",
+		"dns://" + resolver + "/api.test?count=3":                "expected 3 A records for api.test., got 2",
+		"dns://" + resolver + "/api.test?min_ttl=2m":             "TTL 1m0s of A record 10.0.0.1 is out of range",
+		"dns://" + resolver + "/missing.test":                    "resolving missing.test. A failed: NXDOMAIN",
+		"dns://" + resolver + "/api.example":                     "failed: SERVFAIL",
+		"dns://" + resolver + "/empty.test":                      "no A records for empty.test.",
+		"dns://" + resolver + "/api.test?type=SRV":               "unsupported record type: SRV",
+		"dns://" + closedUDPPort(t) + "/api.test?type=A&count=1": "",
+	}
+	for url, message := range failing {
+		result, err := dnsService.CheckUrl(url)
+		require.Error(t, err, url)
+		assert.False(t, result.IsOk, url)
+		assert.Contains(t, result.ErrorMessage(), message, url)
+	}
+
+	assert.NoError(t, defaultRegistry.ValidateURL("dns://10.0.0.2/api.internal?type=A"))
+	assert.NoError(t, defaultRegistry.ValidateURL("dns://10.0.0.2:5353/api.internal"))
+	assert.Error(t, defaultRegistry.ValidateURL("dns://10.0.0.2"))
+}
+
+// closedUDPPort returns the address of a local UDP port nobody listens on.
+func closedUDPPort(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := conn.LocalAddr().String()
+	require.NoError(t, conn.Close())
+	return address
+}
+
+func TestDNSMessageRoundTrip(t *testing.T) {
+	t.Parallel()
+	message := dns.Message{
+		ID:        42,
+		Response:  true,
+		Questions: []dns.Question{{Name: "www.test.", Type: dns.TypeCNAME}},
+		Answers: []dns.Record{
+			{Name: "www.test.", Type: dns.TypeCNAME, TTL: 120, Value: "web.test."},
+			{Name: "test.", Type: dns.TypeNS, TTL: 86400, Value: "ns1.test."},
+			{Name: "test.", Type: dns.TypeTXT, TTL: 1, Value: strings.Repeat("x", 300)},
+		},
+	}
+	packed, err := message.Pack()
+	require.NoError(t, err)
+	unpacked, err := dns.Unpack(packed)
+	require.NoError(t, err)
+	assert.Equal(t, message, unpacked)
+
+	// Names compressed with a pointer to the question
+	compressed := append([]byte{}, packed[:12]...)
+	compressed[7] = 1 // One answer
+	compressed = append(compressed, packed[12:12+len("\x03www\x04test\x00")+4]...)
+	compressed = append(compressed, 0xC0, 12, 0, 5, 0, 1, 0, 0, 0, 120, 0, 6, 3, 'w', 'e', 'b', 0xC0, 16)
+	unpacked, err = dns.Unpack(compressed)
+	require.NoError(t, err)
+	require.Len(t, unpacked.Answers, 1)
+	assert.Equal(t, dns.Record{Name: "www.test.", Type: dns.TypeCNAME, TTL: 120, Value: "web.test."}, unpacked.Answers[0])
+
+	_, err = dns.Unpack(packed[:20])
+	assert.Error(t, err)
+}
@@ -219,9 +219,9 @@ func TestInvalidArgs(t *testing.T) {
 		"htt://invalid-url",
 		"://missing-scheme",
 		"http:/missing-slash",
-		"",                      // Empty string
-		"http://invalid@host$",  // Invalid characters
-		"ftp://unsupported.com", // Unsupported scheme
+		"",                         // Empty string
+		"http://invalid@host$",     // Invalid characters
+		"gopher://unsupported.com", // Unsupported scheme
 		"https://:443/path",
 	}
 
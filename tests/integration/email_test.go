@@ -0,0 +1,162 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/tests"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTLSConfigs(t *testing.T) (server *tls.Config, client *tls.Config) {
+	certificate, pool, err := tests.SelfSignedCertificate(time.Now().Add(24 * time.Hour))
+	require.NoError(t, err)
+	return &tls.Config{Certificates: []tls.Certificate{certificate}},
+		&tls.Config{ServerName: "127.0.0.1", RootCAs: pool}
+}
+
+func downEvent(target string, err string) notifier.Event {
+	return notifier.Event{
+		Target:    target,
+		OldState:  model.StateUp,
+		NewState:  model.StateDown,
+		Error:     err,
+		Latency:   30 * time.Millisecond,
+		Timestamp: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestEmailOverStartTLSWithAuth(t *testing.T) {
+	t.Parallel()
+	serverTLS, clientTLS := newTLSConfigs(t)
+	server, err := tests.NewFakeSMTPServer(serverTLS, false)
+	require.NoError(t, err)
+	defer server.Close()
+
+	smtpNotifier := notifier.NewSMTPNotifier(notifier.SMTPSettings{
+		Host:     "127.0.0.1",
+		Port:     server.Port(),
+		Username: "alerts",
+		Password: "secret",
+		From:     "hc@example.com",
+		To:       []string{"oncall@example.com", "team@example.com"},
+	}).WithTLSConfig(clientTLS)
+
+	event := downEvent("https://mail.com", "connection refused")
+	failure := model.NewHealthCheckResultWithError(errors.New("connection refused"), 30*time.Millisecond)
+	failure.Timestamp = event.Timestamp
+	event.History = []model.HealthCheckResult{resultAt(event.Timestamp.Add(-time.Minute), 200, 25*time.Millisecond), failure}
+
+	assert.Equal(t, notifier.DefaultSMTPBatchWindow, smtpNotifier.BatchWindow())
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{smtpNotifier}, fastRetries())
+	dispatcher.Dispatch(event)
+	dispatcher.Close()
+	require.True(t, dispatcher.Deliveries()[0].Succeeded(), dispatcher.Deliveries()[0].Error)
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.True(t, messages[0].TLS)
+	assert.Equal(t, "alerts", messages[0].Username)
+	assert.Equal(t, "hc@example.com", messages[0].From)
+	assert.Equal(t, []string{"oncall@example.com", "team@example.com"}, messages[0].To)
+	assert.Contains(t, messages[0].Data, "Subject: [DOWN] https://mail.com\r\n")
+	assert.Contains(t, messages[0].Data, "To: oncall@example.com, team@example.com\r\n")
+	assert.Contains(t, messages[0].Data, "Last error:  connection refused\r\n")
+	assert.Contains(t, messages[0].Data, "Recent checks:\r\n")
+	assert.Contains(t, messages[0].Data, "2025-01-01T11:59:00Z  OK    25ms  HTTP 200\r\n")
+	assert.Contains(t, messages[0].Data, "2025-01-01T12:00:00Z  FAIL  30ms  connection refused\r\n")
+}
+
+func TestEmailHistoryOfChecksWithoutStatusCodes(t *testing.T) {
+	t.Parallel()
+	serverTLS, clientTLS := newTLSConfigs(t)
+	server, err := tests.NewFakeSMTPServer(serverTLS, false)
+	require.NoError(t, err)
+	defer server.Close()
+
+	smtpNotifier := notifier.NewSMTPNotifier(notifier.SMTPSettings{
+		Host: "127.0.0.1",
+		Port: server.Port(),
+		From: "hc@example.com",
+		To:   []string{"oncall@example.com"},
+	}).WithTLSConfig(clientTLS)
+
+	event := downEvent("tcp://db.internal:5432", "connection refused")
+	passed := model.NewSuccessfulResult(5*time.Millisecond, 0)
+	passed.Timestamp = event.Timestamp.Add(-2 * time.Minute)
+	warning := model.NewSuccessfulResult(7*time.Millisecond, 0)
+	warning.Timestamp, warning.Output = event.Timestamp.Add(-time.Minute), "DISK WARNING - free space: / 900 MB"
+	failure := model.HealthCheckResult{Latency: 9 * time.Millisecond, Timestamp: event.Timestamp}
+	event.History = []model.HealthCheckResult{passed, warning, failure}
+
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{smtpNotifier}, fastRetries())
+	dispatcher.Dispatch(event)
+	dispatcher.Close()
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0].Data, "2025-01-01T11:58:00Z  OK    5ms  OK\r\n")
+	assert.Contains(t, messages[0].Data, "2025-01-01T11:59:00Z  OK    7ms  DISK WARNING - free space: / 900 MB\r\n")
+	assert.Contains(t, messages[0].Data, "2025-01-01T12:00:00Z  FAIL  9ms  check failed\r\n")
+	assert.NotContains(t, messages[0].Data, "HTTP 0")
+}
+
+func TestEmailBatchesSimultaneousFailures(t *testing.T) {
+	t.Parallel()
+	serverTLS, clientTLS := newTLSConfigs(t)
+	server, err := tests.NewFakeSMTPServer(serverTLS, true)
+	require.NoError(t, err)
+	defer server.Close()
+
+	smtpNotifier := notifier.NewSMTPNotifier(notifier.SMTPSettings{
+		Host:        "127.0.0.1",
+		Port:        server.Port(),
+		From:        "hc@example.com",
+		To:          []string{"oncall@example.com"},
+		TLS:         notifier.SMTPTLSImplicit,
+		BatchWindow: 200 * time.Millisecond,
+	}).WithTLSConfig(clientTLS)
+
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{smtpNotifier}, fastRetries())
+	dispatcher.Dispatch(downEvent("https://a.com", "HTTP 502"))
+	dispatcher.Dispatch(downEvent("https://b.com", "HTTP 502"))
+	dispatcher.Dispatch(downEvent("https://c.com", "timeout"))
+	dispatcher.Close()
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.True(t, messages[0].TLS)
+	assert.Contains(t, messages[0].Data, "Subject: [DOWN] 3 targets: https://a.com, https://b.com, https://c.com\r\n")
+	assert.Contains(t, messages[0].Data, "https://c.com is DOWN: timeout")
+	deliveries := dispatcher.Deliveries()
+	require.Len(t, deliveries, 1)
+	assert.Len(t, deliveries[0].Events, 3)
+}
+
+func TestEmailRequiresStartTLS(t *testing.T) {
+	t.Parallel()
+	server, err := tests.NewFakeSMTPServer(nil, false)
+	require.NoError(t, err)
+	defer server.Close()
+
+	options := fastRetries()
+	options.Retries = 0
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewSMTPNotifier(notifier.SMTPSettings{
+		Host: "127.0.0.1",
+		Port: server.Port(),
+		From: "hc@example.com",
+		To:   []string{"oncall@example.com"},
+	})}, options)
+	dispatcher.Dispatch(downEvent("https://plain.com", "HTTP 500"))
+	dispatcher.Close()
+
+	deliveries := dispatcher.Deliveries()
+	require.Len(t, deliveries, 1)
+	assert.Contains(t, deliveries[0].Error, "STARTTLS")
+	assert.Empty(t, server.Messages())
+}
@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/api"
+	"GoHealthChecker/internal/config"
+	"GoHealthChecker/internal/escalation"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscalationRemindsAndEscalatesUntilRecovery(t *testing.T) {
+	t.Parallel()
+	primary := &webhookReceiver{}
+	primaryServer := httptest.NewServer(primary.handler(func() int { return http.StatusOK }))
+	defer primaryServer.Close()
+	oncall := &webhookReceiver{}
+	oncallServer := httptest.NewServer(oncall.handler(func() int { return http.StatusOK }))
+	defer oncallServer.Close()
+
+	appConfig, err := config.Parse([]byte(`{
+		"targets": [{"url": "https://db.com", "tags": ["critical"]}],
+		"escalations": [{
+			"name": "critical",
+			"tags": ["critical"],
+			"repeat_interval": "5m",
+			"escalate_after": "15m",
+			"escalate_to": [{"type": "webhook", "url": "` + oncallServer.URL + `"}]
+		}]
+	}`))
+	require.NoError(t, err)
+	manager, err := appConfig.NewEscalations()
+	require.NoError(t, err)
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(primaryServer.URL, nil)}, fastRetries())
+	manager.WithDispatcher(dispatcher)
+
+	down := downEvent("https://db.com", "connection refused")
+	start := down.Timestamp
+	manager.Observe(down)
+	// Targets without a policy are not followed
+	manager.Observe(downEvent("https://other.com", "timeout"))
+	require.Len(t, manager.Outages(), 1)
+
+	assert.Empty(t, manager.Check(start.Add(4*time.Minute)))
+	reminders := manager.Check(start.Add(5 * time.Minute))
+	require.Len(t, reminders, 1)
+	assert.Equal(t, "https://db.com is still DOWN after 5m0s: connection refused (reminder 1)", reminders[0].Summary())
+	assert.Len(t, manager.Check(start.Add(10*time.Minute)), 1)
+
+	escalated := manager.Check(start.Add(15 * time.Minute))
+	require.Len(t, escalated, 2)
+	assert.Equal(t, "[ESCALATED] https://db.com is DOWN for 15m0s: connection refused", escalated[0].Summary())
+	assert.Equal(t, 3, escalated[1].Escalation.Reminder)
+
+	recovery := notifier.Event{
+		Target:           "https://db.com",
+		OldState:         model.StateDown,
+		NewState:         model.StateUp,
+		Timestamp:        start.Add(17 * time.Minute),
+		IncidentDuration: 17 * time.Minute,
+	}
+	manager.Observe(recovery)
+	assert.Empty(t, manager.Outages())
+	assert.Empty(t, manager.Check(start.Add(time.Hour)))
+	dispatcher.Close()
+	manager.Close()
+
+	// Reminders go to the primary channels, the escalation channels get the escalation,
+	// the reminders after it and the recovery
+	assert.Len(t, primary.received(), 3)
+	received := oncall.received()
+	require.Len(t, received, 3)
+	escalation := received[0]["escalation"].(map[string]any)
+	assert.Equal(t, "critical", escalation["policy"])
+	assert.Equal(t, 0.0, escalation["reminder"])
+	assert.Equal(t, 900.0, escalation["down_for_seconds"])
+	assert.Equal(t, 3.0, received[1]["escalation"].(map[string]any)["reminder"])
+	assert.Equal(t, "UP", received[2]["new_state"])
+	assert.Nil(t, received[2]["escalation"])
+}
+
+func TestAcknowledgeStopsRemindersThroughControlAPI(t *testing.T) {
+	t.Parallel()
+	manager := escalation.NewManager([]*escalation.Policy{
+		{Name: "default", Targets: []string{"*"}, RepeatInterval: time.Minute},
+	}, nil, time.Minute)
+	server := httptest.NewServer(api.NewServer(nil).WithEscalations(manager).Handler())
+	defer server.Close()
+	client := api.NewClient(server.URL)
+
+	down := downEvent("https://api.com", "HTTP 500")
+	manager.Observe(down)
+	require.Len(t, manager.Check(down.Timestamp.Add(time.Minute)), 1)
+
+	outages, err := client.Outages()
+	require.NoError(t, err)
+	require.Len(t, outages, 1)
+	assert.Equal(t, "https://api.com", outages[0].Target)
+	assert.Equal(t, 1, outages[0].Reminders)
+	assert.False(t, outages[0].Acknowledged)
+
+	outage, err := client.Acknowledge(api.AckRequest{Target: "https://api.com", By: "alice", Comment: "restarting"})
+	require.NoError(t, err)
+	assert.True(t, outage.Acknowledged)
+	assert.Equal(t, "alice", outage.AcknowledgedBy)
+	assert.Empty(t, manager.Check(down.Timestamp.Add(time.Hour)))
+
+	_, err = client.Acknowledge(api.AckRequest{Target: "https://up.com"})
+	assert.ErrorContains(t, err, "https://up.com is not DOWN")
+	_, err = client.Acknowledge(api.AckRequest{})
+	assert.ErrorContains(t, err, "target is required")
+
+	// The next outage is followed again
+	manager.Observe(notifier.Event{Target: "https://api.com", OldState: model.StateDown, NewState: model.StateUp, Timestamp: down.Timestamp.Add(time.Hour)})
+	down.Timestamp = down.Timestamp.Add(2 * time.Hour)
+	manager.Observe(down)
+	assert.Len(t, manager.Check(down.Timestamp.Add(time.Minute)), 1)
+}
+
+func TestEscalationConfigValidation(t *testing.T) {
+	t.Parallel()
+	for _, invalid := range []string{
+		`{"escalations": [{"name": "a", "repeat_interval": "5m"}]}`,
+		`{"escalations": [{"name": "a", "targets": ["*"]}]}`,
+		`{"escalations": [{"name": "a", "targets": ["*"], "escalate_after": "10m"}]}`,
+		`{"escalations": [{"name": "a", "targets": ["*"], "repeat_interval": "5m", "escalate_to": [{"type": "webhook", "url": "http://x"}]}]}`,
+		`{"escalations": [{"name": "a", "targets": ["*"], "escalate_after": "10m", "escalate_to": [{"type": "webhook"}]}]}`,
+	} {
+		_, err := config.Parse([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
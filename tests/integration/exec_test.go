@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/config"
+	"GoHealthChecker/internal/notifier"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript creates an executable shell script in a temporary directory.
+// Tests running scripts are not parallel, a concurrent fork may keep the script
+// open for writing and fail its execution with "text file busy".
+func writeScript(t *testing.T, body string) string {
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755))
+	return path
+}
+
+func TestExecNotifierPassesEventToCommand(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, `
+echo "$HC_TARGET $HC_OLD_STATE $HC_NEW_STATE $HC_STATUS_CODE $HC_EVENT" > "$1/env.txt"
+echo "$HC_ERROR" >> "$1/env.txt"
+cat > "$1/stdin.json"
+echo "restarted"
+`)
+	appConfig, err := config.Parse([]byte(`{"notifiers": [{"type": "exec", "command": ["` + script + `", "` + dir + `"], "timeout": "5s"}]}`))
+	require.NoError(t, err)
+	notifiers, err := appConfig.NewNotifiers()
+	require.NoError(t, err)
+
+	dispatcher := notifier.NewDispatcher(notifiers, fastRetries())
+	event := downEvent("https://container.com", "HTTP 502")
+	event.StatusCode = 502
+	dispatcher.Dispatch(event)
+	dispatcher.Close()
+	require.True(t, dispatcher.Deliveries()[0].Succeeded(), dispatcher.Deliveries()[0].Error)
+
+	environment, err := os.ReadFile(filepath.Join(dir, "env.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://container.com UP DOWN 502 state_change\nHTTP 502\n", string(environment))
+
+	stdin, err := os.ReadFile(filepath.Join(dir, "stdin.json"))
+	require.NoError(t, err)
+	payload := make(map[string]any)
+	require.NoError(t, json.Unmarshal(stdin, &payload))
+	assert.Equal(t, "https://container.com", payload["target"])
+	assert.Equal(t, "DOWN", payload["new_state"])
+	assert.Equal(t, "HTTP 502", payload["error"])
+}
+
+func TestExecNotifierFailsOnExitCode(t *testing.T) {
+	script := writeScript(t, "echo 'ticket system unavailable' >&2\nexit 3\n")
+	// Failed commands are not run again unless retries are set
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{
+		notifier.NewExecNotifier([]string{script}),
+		notifier.NewExecNotifier([]string{script}).WithRetries(1),
+	}, fastRetries())
+	dispatcher.Dispatch(downEvent("https://ticket.com", "timeout"))
+	dispatcher.Close()
+
+	deliveries := dispatcher.Deliveries()
+	require.Len(t, deliveries, 2)
+	attempts := []int{deliveries[0].Attempts, deliveries[1].Attempts}
+	assert.ElementsMatch(t, []int{1, 2}, attempts)
+	for _, delivery := range deliveries {
+		assert.False(t, delivery.Succeeded())
+		assert.Contains(t, delivery.Error, "exit code 3")
+		assert.Contains(t, delivery.Error, "ticket system unavailable")
+	}
+
+	_, err := config.Parse([]byte(`{"notifiers": [{"type": "exec", "command": ["` + script + `"], "retries": -1}]}`))
+	assert.ErrorContains(t, err, "retries must not be negative")
+}
+
+func TestExecNotifierRunsOnlyForStateChanges(t *testing.T) {
+	dir := t.TempDir()
+	script := writeScript(t, `echo "$HC_TARGET $HC_EVENT" >> "$1/events.txt"`+"\n")
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{
+		notifier.NewExecNotifier([]string{script, dir}),
+	}, fastRetries())
+	flap := downEvent("https://flapping.com", "HTTP 502")
+	flap.Flap = &notifier.Flap{Flapping: true, Changes: 5, Window: time.Minute}
+	dispatcher.Dispatch(flap)
+	group := downEvent("https://a.com", "HTTP 502")
+	group.Group = &notifier.Group{Events: []notifier.Event{downEvent("https://a.com", "HTTP 502"), downEvent("https://b.com", "HTTP 502")}}
+	dispatcher.Dispatch(group)
+	dispatcher.Close()
+
+	// The members of the group are passed one by one, the flapping is not passed
+	events, err := os.ReadFile(filepath.Join(dir, "events.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.com state_change\nhttps://b.com state_change\n", string(events))
+}
+
+func TestExecNotifierTimeout(t *testing.T) {
+	script := writeScript(t, "sleep 10\n")
+	options := fastRetries()
+	options.Retries = 0
+	options.Timeout = 10 * time.Second
+	execNotifier := notifier.NewExecNotifier([]string{script}).WithTimeout(200 * time.Millisecond)
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{execNotifier}, options)
+
+	start := time.Now()
+	dispatcher.Dispatch(downEvent("https://slow-hook.com", "timeout"))
+	dispatcher.Close()
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	deliveries := dispatcher.Deliveries()
+	require.Len(t, deliveries, 1)
+	assert.Contains(t, deliveries[0].Error, "timed out")
+
+	_, err := config.Parse([]byte(`{"notifiers": [{"type": "exec"}]}`))
+	assert.ErrorContains(t, err, "exec requires command")
+}
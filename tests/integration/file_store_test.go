@@ -0,0 +1,77 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/store"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStoreRebuildsMetricsAfterRestart(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	fileStore, err := store.NewFileStore(dir)
+	require.NoError(t, err)
+	fileStore.SaveResult("https://filestore.com", model.NewHealthCheckResult(200, 100*time.Millisecond, 40))
+	fileStore.SaveResult("https://filestore.com", model.NewHealthCheckResult(200, 300*time.Millisecond, 60))
+	fileStore.SaveResult("https://filestore.com", model.NewHealthCheckResultWithError(errors.New("connection refused"), 50*time.Millisecond))
+	require.NoError(t, fileStore.Close())
+
+	// Simulate a restart by opening the same directory again
+	reopened, err := store.NewStore("file:"+dir, store.DefaultOptions())
+	require.NoError(t, err)
+	defer func() { _ = reopened.Close() }()
+
+	metrics := reopened.GetMetrics()["https://filestore.com"]
+	assert.Equal(t, 3, metrics.TotalRequests)
+	assert.Equal(t, 2, metrics.SuccessRequests)
+	assert.Equal(t, 1, metrics.FailedRequests)
+	assert.Equal(t, 50.0, metrics.LatencyMin)
+	assert.Equal(t, 300.0, metrics.LatencyMax)
+
+	latest := reopened.GetLatestResults()["https://filestore.com"]
+	assert.False(t, latest.IsOk)
+	require.Error(t, latest.Error)
+	assert.Equal(t, "connection refused", latest.Error.Error())
+}
+
+func TestFileStoreSkipsCorruptedRecords(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	fileStore, err := store.NewFileStore(dir)
+	require.NoError(t, err)
+	fileStore.SaveResult("https://corrupted.com", model.NewHealthCheckResult(200, 100*time.Millisecond, 40))
+	require.NoError(t, fileStore.Close())
+
+	// Append a half-written record, as if the process crashed mid-write
+	segments, err := filepath.Glob(filepath.Join(dir, "results-*.jsonl"))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	file, err := os.OpenFile(segments[0], os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"url":"https://corrupted.com","status_co`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	reopened, err := store.NewFileStore(dir)
+	require.NoError(t, err)
+	defer func() { _ = reopened.Close() }()
+	assert.Equal(t, 1, reopened.GetMetrics()["https://corrupted.com"].TotalRequests)
+}
+
+func TestUnsupportedStoreSpec(t *testing.T) {
+	t.Parallel()
+
+	_, err := store.NewStore("redis:localhost", store.DefaultOptions())
+	assert.Error(t, err)
+	_, err = store.NewStore("file:", store.DefaultOptions())
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/controller"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/internal/state"
+	"GoHealthChecker/internal/store"
+	"GoHealthChecker/internal/view"
+	"GoHealthChecker/tests"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlapDetection(t *testing.T) {
+	t.Parallel()
+	settings := model.NewAppSettings().WithFlapDetection(time.Minute, 3)
+	tracker := state.NewTracker(*settings)
+	detector := state.NewFlapDetector(*settings)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	url := "https://flapping.com"
+
+	// UP, then alternating every 10s: 5 state changes within a minute
+	statuses := []int{200, 500, 200, 500, 200, 500, 500, 500, 500, 500, 500, 500, 500, 500}
+	changes := make([]state.FlapChange, 0)
+	flapping := make([]bool, 0)
+	for i, status := range statuses {
+		result, transition := tracker.Observe(url, resultAt(start.Add(time.Duration(i)*10*time.Second), status, 0))
+		result, change := detector.Observe(url, result, transition)
+		flapping = append(flapping, result.Flapping)
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+
+	require.Len(t, changes, 2)
+	assert.True(t, changes[0].Flapping)
+	assert.Equal(t, 4, changes[0].Changes)
+	assert.Equal(t, start.Add(40*time.Second), changes[0].Timestamp)
+	// The fourth change is not notified anymore, the third one was
+	assert.Equal(t, model.StateDown, changes[0].Notified)
+
+	// Stable once at most one change is left within the minute
+	assert.False(t, changes[1].Flapping)
+	assert.Equal(t, model.StateDown, changes[1].State)
+	assert.Equal(t, 1, changes[1].Changes)
+	assert.Equal(t, model.StateDown, changes[1].Notified)
+	assert.Equal(t, start.Add(110*time.Second), changes[1].Timestamp)
+	assert.Equal(t, []bool{false, false, false, false, true, true, true, true, true, true, true, false, false, false}, flapping)
+	assert.False(t, detector.Flapping(url))
+}
+
+func TestControllerSuppressesNotificationsWhileFlapping(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	// UP, DOWN, UP, DOWN, UP, DOWN, UP, UP, ...
+	var calls atomic.Int32
+	httpmockTransport := httpmock.NewMockTransport()
+	httpmockTransport.RegisterResponder("GET", "https://bouncing.com", func(req *http.Request) (*http.Response, error) {
+		call := calls.Add(1)
+		if call <= 6 && call%2 == 0 {
+			return httpmock.NewStringResponse(503, "unavailable"), nil
+		}
+		return httpmock.NewStringResponse(200, "ok"), nil
+	})
+
+	_, _, cancel, settings := tests.CreateConfiguration(1, 1)
+	settings.WithPollingInterval(100*time.Millisecond).WithFlapDetection(time.Second, 2)
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	appController := controller.NewController(
+		store.NewInMemoryStore(),
+		view.NewCLIView(settings),
+		service.NewHTTPServiceWithTransport(httpmockTransport, settings),
+		settings,
+	).WithDispatcher(dispatcher)
+
+	done := make(chan struct{})
+	go func() {
+		_ = appController.Start([]string{"https://bouncing.com"})
+		close(done)
+	}()
+	time.Sleep(2500 * time.Millisecond)
+	cancel()
+	<-done
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	states := make([]any, 0, len(payloads))
+	for _, payload := range payloads {
+		states = append(states, payload["new_state"])
+	}
+	// DOWN and UP are notified, the third change starts flapping, the rest is suppressed
+	assert.Equal(t, []any{"DOWN", "UP", "FLAPPING", "UP"}, states)
+	require.Len(t, payloads, 4)
+	assert.Equal(t, "FLAPPING", payloads[3]["old_state"])
+}
@@ -0,0 +1,184 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/config"
+	"GoHealthChecker/internal/notifier"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recoveryAt(target string, at time.Time) notifier.Event {
+	event := recoveryEvent(target)
+	event.Timestamp = at
+	return event
+}
+
+func TestGroupingAggregatesTargetsSharingLabel(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	appConfig, err := config.Parse([]byte(`{
+		"targets": [
+			{"url": "https://pay-1.com", "labels": {"group": "payments"}},
+			{"url": "https://pay-2.com", "labels": {"group": "payments"}},
+			{"url": "https://pay-3.com", "labels": {"group": "payments"}},
+			{"url": "https://search.com", "labels": {"group": "search"}}
+		],
+		"grouping": {"window": "100ms", "by": ["group"]}
+	}`))
+	require.NoError(t, err)
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	dispatcher.WithGrouping(appConfig.NewGrouper())
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, target := range []string{"https://pay-1.com", "https://pay-2.com", "https://pay-3.com"} {
+		dispatcher.Dispatch(downEvent(target, "connection refused"))
+	}
+	// Single target of its group and targets without the label are not aggregated
+	dispatcher.Dispatch(downEvent("https://search.com", "HTTP 500"))
+	dispatcher.Dispatch(downEvent("https://unlabeled.com", "HTTP 500"))
+	require.Eventually(t, func() bool { return len(receiver.received()) == 4 }, 2*time.Second, 10*time.Millisecond)
+
+	dispatcher.Dispatch(recoveryAt("https://pay-1.com", start.Add(time.Minute)))
+	dispatcher.Dispatch(recoveryAt("https://pay-3.com", start.Add(2*time.Minute)))
+	dispatcher.Dispatch(recoveryAt("https://search.com", start.Add(2*time.Minute)))
+	dispatcher.Dispatch(recoveryAt("https://pay-2.com", start.Add(3*time.Minute)))
+	dispatcher.Close()
+
+	byTarget := make(map[string][]map[string]any)
+	for _, payload := range receiver.received() {
+		byTarget[payload["target"].(string)] = append(byTarget[payload["target"].(string)], payload)
+	}
+	assert.Len(t, byTarget["https://unlabeled.com"], 1)
+	assert.Len(t, byTarget["https://search.com"], 2)
+	// The first DOWN of the group is sent right away, its recovery waits for the group
+	require.Len(t, byTarget["https://pay-1.com"], 1)
+	assert.Equal(t, "DOWN", byTarget["https://pay-1.com"][0]["new_state"])
+	require.Len(t, byTarget["group=payments"], 2)
+
+	down := byTarget["group=payments"][0]
+	assert.Equal(t, "DOWN", down["new_state"])
+	assert.Equal(t, "connection refused", down["error"])
+	group := down["group"].(map[string]any)
+	assert.Equal(t, 3.0, group["affected"])
+	require.Len(t, group["targets"], 2)
+	assert.Equal(t, "https://pay-2.com", group["targets"].([]any)[0].(map[string]any)["target"])
+
+	recovery := byTarget["group=payments"][1]
+	assert.Equal(t, "UP", recovery["new_state"])
+	assert.Equal(t, 180.0, recovery["incident_duration_seconds"])
+	assert.Len(t, recovery["group"].(map[string]any)["targets"], 3)
+
+	deliveries := dispatcher.Deliveries()
+	summaries := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		summaries = append(summaries, delivery.Events[0].Summary())
+	}
+	assert.Contains(t, summaries, "2 more targets of group=payments are DOWN (3 in total): "+
+		"https://pay-2.com (connection refused), https://pay-3.com (connection refused)")
+	assert.Contains(t, summaries, "All 3 targets of group=payments are UP again after 3m0s: https://pay-1.com, https://pay-3.com, https://pay-2.com")
+}
+
+func TestGroupingCountsTargetsFailingAgainOnce(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	dispatcher.WithGrouping(notifier.NewGrouper(time.Minute, nil, nil).WithErrorClass())
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	dispatcher.Dispatch(downEvent("https://api-1.com", "timeout"))
+	dispatcher.Dispatch(downEvent("https://api-2.com", "timeout"))
+	dispatcher.Dispatch(downEvent("https://api-3.com", "timeout"))
+	dispatcher.Dispatch(recoveryAt("https://api-2.com", start.Add(time.Second)))
+	dispatcher.Dispatch(downEvent("https://api-2.com", "i/o timeout"))
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "https://api-1.com", payloads[0]["target"])
+	group := payloads[1]["group"].(map[string]any)
+	assert.Equal(t, 3.0, group["affected"])
+	require.Len(t, group["targets"], 2)
+	assert.Equal(t, "i/o timeout", group["targets"].([]any)[0].(map[string]any)["error"])
+	assert.Equal(t, "2 more targets of error=timeout are DOWN (3 in total): https://api-2.com (i/o timeout), https://api-3.com (timeout)",
+		dispatcher.Deliveries()[1].Events[0].Summary())
+}
+
+func TestGroupingSendsLoneTargetsRightAway(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	dispatcher.WithGrouping(notifier.NewGrouper(time.Hour, nil, nil).WithErrorClass())
+	defer dispatcher.Close()
+
+	// Neither the DOWN nor the recovery of a single target waits for the window of an hour
+	dispatcher.Dispatch(downEvent("https://lone.com", "connection refused"))
+	require.Eventually(t, func() bool { return len(receiver.received()) == 1 }, time.Second, 10*time.Millisecond)
+	dispatcher.Dispatch(recoveryEvent("https://lone.com"))
+	require.Eventually(t, func() bool { return len(receiver.received()) == 2 }, time.Second, 10*time.Millisecond)
+
+	payloads := receiver.received()
+	assert.Equal(t, "https://lone.com", payloads[0]["target"])
+	assert.Equal(t, "DOWN", payloads[0]["new_state"])
+	assert.Nil(t, payloads[0]["group"])
+	assert.Equal(t, "UP", payloads[1]["new_state"])
+}
+
+func TestGroupingByErrorClass(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		`Get "https://a.com": context deadline exceeded (Client.Timeout exceeded while awaiting headers)`: "timeout",
+		`dial tcp 10.0.0.1:443: connect: connection refused`:                                              "connection refused",
+		`dial tcp: lookup nowhere.invalid: no such host`:                                                  "dns",
+		`tls: failed to verify certificate: x509: certificate has expired`:                                "tls",
+		"HTTP 503": "HTTP 5xx",
+		"HTTP 404": "HTTP 4xx",
+		"bad body": "bad body",
+	}
+	for message, class := range cases {
+		assert.Equal(t, class, notifier.ErrorClass(message), message)
+	}
+
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	dispatcher.WithGrouping(notifier.NewGrouper(time.Minute, nil, nil).WithErrorClass())
+
+	dispatcher.Dispatch(downEvent("https://a.com", "context deadline exceeded"))
+	dispatcher.Dispatch(downEvent("https://b.com", "i/o timeout"))
+	dispatcher.Dispatch(downEvent("https://c.com", "HTTP 502"))
+	dispatcher.Dispatch(downEvent("https://d.com", "HTTP 503"))
+	dispatcher.Dispatch(downEvent("https://e.com", "read: i/o timeout"))
+	dispatcher.Dispatch(downEvent("https://f.com", "HTTP 500"))
+	// Closing delivers the groups without waiting for the end of the window
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 4)
+	targets := make([]any, 0, len(payloads))
+	for _, payload := range payloads {
+		targets = append(targets, payload["target"])
+		if payload["group"] == nil {
+			continue
+		}
+		assert.Len(t, payload["group"].(map[string]any)["targets"], 2)
+		assert.Nil(t, payload["error"], "targets failed with different errors of the same class")
+	}
+	assert.ElementsMatch(t, []any{"https://a.com", "https://c.com", "error=timeout", "error=HTTP 5xx"}, targets)
+
+	_, err := config.Parse([]byte(`{"grouping": {"window": "-1s", "by": ["group"]}}`))
+	assert.Error(t, err)
+}
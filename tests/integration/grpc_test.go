@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/tests"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// grpcHealthHandler implements grpc.health.v1.Health/Check with the serving statuses of the services.
+// Calls without the x-api-key metadata are rejected as UNAUTHENTICATED.
+func grpcHealthHandler(statuses map[string]byte) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /grpc.health.v1.Health/Check", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		if r.Header.Get("X-Api-Key") != "secret" {
+			// Trailers-only response
+			w.Header().Set("Grpc-Status", "16")
+			w.Header().Set("Grpc-Message", "missing%20api%20key")
+			return
+		}
+		// HealthCheckRequest{service} is field 1, the only one
+		var name string
+		if len(body) > 7 && body[5] == 0x0A {
+			name = string(body[7 : 7+int(body[6])])
+		}
+		status, known := statuses[name]
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		if !known {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			return
+		}
+		message := []byte{0x08, status}
+		frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(message)))
+		_, _ = w.Write(append(frame, message...))
+		w.Header().Set("Grpc-Status", "0")
+	})
+	return mux
+}
+
+// startH2CServer serves handler over plaintext HTTP/2 on a random local port.
+func startH2CServer(t *testing.T, handler http.Handler) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: handler, Protocols: &http.Protocols{}, ReadHeaderTimeout: time.Second}
+	server.Protocols.SetUnencryptedHTTP2(true)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return listener.Addr().String()
+}
+
+func TestGRPCHealthCheck(t *testing.T) {
+	t.Parallel()
+	statuses := map[string]byte{"": 1, "payments.Payments": 1, "search.Search": 2, "legacy.Legacy": 0, "catalog.Catalog": 3}
+	address := startH2CServer(t, grpcHealthHandler(statuses))
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	grpcService := service.NewGRPCService(settings)
+	key := "?metadata=x-api-key:secret"
+
+	for _, url := range []string{"grpc://" + address + key, "grpc://" + address + "/payments.Payments" + key} {
+		result, err := grpcService.CheckUrl(url)
+		require.NoError(t, err, url)
+		assert.True(t, result.IsOk, url)
+		assert.Positive(t, result.Latency, url)
+		assert.True(t, result.CertExpiry.IsZero())
+	}
+
+	failing := map[string]string{
+		"grpc://" + address + "/search.Search" + key:   "health status NOT_SERVING",
+		"grpc://" + address + "/legacy.Legacy" + key:   "health status UNKNOWN",
+		"grpc://" + address + "/catalog.Catalog" + key: "health status SERVICE_UNKNOWN",
+		"grpc://" + address + "/missing.Missing" + key: "gRPC status NOT_FOUND: unknown service",
+		"grpc://" + address + "/payments.Payments":     "gRPC status UNAUTHENTICATED: missing api key",
+		"grpc://" + closedPort(t) + key:                "connection refused",
+	}
+	for url, message := range failing {
+		result, err := grpcService.CheckUrl(url)
+		require.Error(t, err, url)
+		assert.False(t, result.IsOk, url)
+		assert.Contains(t, result.ErrorMessage(), message, url)
+	}
+
+	// Only NOT_SERVING is DOWN, the unknown statuses are inconclusive
+	for name, checkState := range map[string]model.State{"search.Search": "", "legacy.Legacy": model.StateUnknown, "catalog.Catalog": model.StateUnknown} {
+		result, _ := grpcService.CheckUrl("grpc://" + address + "/" + name + key)
+		assert.Equal(t, checkState, result.CheckState, name)
+	}
+
+	assert.NoError(t, defaultRegistry.ValidateURL("grpc://backend.internal:50051/payments.Payments"))
+	assert.Error(t, defaultRegistry.ValidateURL("grpc://backend.internal/payments.Payments"))
+	assert.NoError(t, defaultRegistry.ValidateURL("grpc://backend.internal:50051?metadata=x-tenant:shop&metadata_env=authorization:GRPC_TOKEN"))
+	assert.ErrorContains(t, defaultRegistry.ValidateURL("grpc://backend.internal:50051?metadata=x-api-key:secret"), "use metadata_env")
+}
+
+func TestGRPCMetadataFromEnvironment(t *testing.T) {
+	address := startH2CServer(t, grpcHealthHandler(map[string]byte{"": 1}))
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	t.Setenv("TEST_GRPC_API_KEY", "secret")
+
+	result, err := service.NewGRPCService(settings).CheckUrl("grpc://" + address + "?metadata_env=x-api-key:TEST_GRPC_API_KEY")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+}
+
+func TestGRPCHealthCheckOverTLS(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewUnstartedServer(grpcHealthHandler(map[string]byte{"": 1}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "https://")
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	grpcService := service.NewGRPCService(settings)
+
+	result, err := grpcService.CheckUrl("grpc://" + address + "?tls=true&insecure_skip_verify=true&metadata=x-api-key:secret")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Equal(t, server.Certificate().NotAfter, result.CertExpiry)
+
+	// The test certificate is not trusted without insecure_skip_verify
+	_, err = grpcService.CheckUrl("grpc://" + address + "?tls=true&metadata=x-api-key:secret")
+	assert.ErrorContains(t, err, "certificate")
+}
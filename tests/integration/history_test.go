@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/store"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resultAt(timestamp time.Time, statusCode int, latency time.Duration) model.HealthCheckResult {
+	result := model.NewHealthCheckResult(statusCode, latency, 40)
+	result.Timestamp = timestamp
+	return result
+}
+
+func TestHistoryKeepsLastNResults(t *testing.T) {
+	t.Parallel()
+	memoryStore := store.NewInMemoryStoreWithHistory(store.HistoryLimit{MaxResults: 3})
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		memoryStore.SaveResult("https://history.com", resultAt(start.Add(time.Duration(i)*time.Second), 200, time.Duration(i)*time.Millisecond))
+	}
+
+	history := memoryStore.GetHistory("https://history.com", time.Time{}, time.Time{})
+	require.Len(t, history, 3)
+	assert.Equal(t, start.Add(2*time.Second), history[0].Timestamp)
+	assert.Equal(t, start.Add(4*time.Second), history[2].Timestamp)
+	// Metrics still cover every result
+	assert.Equal(t, 5, memoryStore.GetMetrics()["https://history.com"].TotalRequests)
+	assert.Empty(t, memoryStore.GetHistory("https://unknown.com", time.Time{}, time.Time{}))
+}
+
+func TestHistoryDropsResultsOlderThanMaxAge(t *testing.T) {
+	t.Parallel()
+	memoryStore := store.NewInMemoryStoreWithHistory(store.HistoryLimit{MaxResults: 100, MaxAge: time.Minute})
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 10; i++ {
+		memoryStore.SaveResult("https://maxage.com", resultAt(start.Add(time.Duration(i)*20*time.Second), 200, 0))
+	}
+
+	history := memoryStore.GetHistory("https://maxage.com", time.Time{}, time.Time{})
+	// newest is at 180s, anything before 120s is dropped
+	require.Len(t, history, 4)
+	assert.Equal(t, start.Add(120*time.Second), history[0].Timestamp)
+}
+
+func TestHistoryQueryRange(t *testing.T) {
+	t.Parallel()
+	memoryStore := store.NewInMemoryStore()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 10; i++ {
+		memoryStore.SaveResult("https://range.com", resultAt(start.Add(time.Duration(i)*time.Minute), 200, 0))
+	}
+
+	history := memoryStore.GetHistory("https://range.com", start.Add(3*time.Minute), start.Add(5*time.Minute))
+	require.Len(t, history, 3)
+	assert.Equal(t, start.Add(3*time.Minute), history[0].Timestamp)
+	assert.Equal(t, start.Add(5*time.Minute), history[2].Timestamp)
+
+	assert.Len(t, memoryStore.GetHistory("https://range.com", start.Add(8*time.Minute), time.Time{}), 2)
+}
+
+func TestFileStoreHistoryReadsBeyondMemory(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	options := store.DefaultOptions()
+	options.History = store.HistoryLimit{MaxResults: 2}
+
+	fileStore, err := store.NewFileStoreWithOptions(dir, options)
+	require.NoError(t, err)
+	defer func() { _ = fileStore.Close() }()
+
+	// Results span two days, so two segments are written
+	start := time.Date(2025, 1, 1, 23, 58, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		fileStore.SaveResult("https://filehistory.com", resultAt(start.Add(time.Duration(i)*time.Minute), 200, 0))
+		fileStore.SaveResult("https://other.com", resultAt(start.Add(time.Duration(i)*time.Minute), 500, 0))
+	}
+
+	history := fileStore.GetHistory("https://filehistory.com", time.Time{}, time.Time{})
+	require.Len(t, history, 5)
+	for _, result := range history {
+		assert.True(t, result.IsOk)
+	}
+
+	history = fileStore.GetHistory("https://filehistory.com", start.Add(time.Minute), start.Add(3*time.Minute))
+	require.Len(t, history, 3)
+	assert.Equal(t, start.Add(time.Minute), history[0].Timestamp)
+}
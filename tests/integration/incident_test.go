@@ -0,0 +1,75 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/store"
+	"GoHealthChecker/internal/view"
+	"GoHealthChecker/tests"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidentsAndOutageMetrics(t *testing.T) {
+	t.Parallel()
+	memoryStore := store.NewInMemoryStore()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	url := "https://incidents.com"
+
+	// UP for 10 minutes, DOWN 2 minutes, UP 10 minutes, DOWN 4 minutes, UP, then DOWN again
+	outcomes := []struct {
+		at     time.Duration
+		status int
+	}{
+		{0, 200}, {10 * time.Minute, 500}, {11 * time.Minute, 500}, {12 * time.Minute, 200},
+		{22 * time.Minute, 503}, {26 * time.Minute, 200}, {30 * time.Minute, 200},
+	}
+	for _, outcome := range outcomes {
+		memoryStore.SaveResult(url, resultAt(start.Add(outcome.at), outcome.status, 0))
+	}
+	failure := model.NewHealthCheckResultWithError(errors.New("connection refused"), 0)
+	failure.Timestamp = start.Add(40 * time.Minute)
+	memoryStore.SaveResult(url, failure)
+	failure.Timestamp = start.Add(41 * time.Minute)
+	memoryStore.SaveResult(url, failure)
+
+	incidents := memoryStore.GetIncidents(url)
+	require.Len(t, incidents, 3)
+	assert.Equal(t, start.Add(10*time.Minute), incidents[0].Start)
+	assert.Equal(t, start.Add(12*time.Minute), incidents[0].End)
+	assert.Equal(t, 2*time.Minute, incidents[0].Duration)
+	assert.Equal(t, "HTTP 500", incidents[0].FirstError)
+	assert.Equal(t, 2, incidents[0].FailedChecks)
+	assert.Equal(t, 4*time.Minute, incidents[1].Duration)
+	assert.True(t, incidents[2].Ongoing())
+	assert.Equal(t, "connection refused", incidents[2].FirstError)
+	assert.Equal(t, time.Minute, incidents[2].Duration)
+
+	metrics := memoryStore.GetMetrics()[url]
+	assert.Equal(t, 3, metrics.Incidents)
+	assert.Equal(t, 3*time.Minute, metrics.MTTR)
+	// 41 minutes observed, 7 minutes down, 3 incidents
+	assert.Equal(t, 34*time.Minute/3, metrics.MTBF)
+	assert.Equal(t, 4*time.Minute, metrics.LongestOutage)
+	assert.Equal(t, 2, metrics.CurrentStreak)
+	assert.False(t, metrics.CurrentStreakOk)
+}
+
+func TestRenderMetricsShowsOutageStatistics(t *testing.T) {
+	t.Parallel()
+	output, _, _, settings := tests.CreateConfiguration(1, 1)
+	memoryStore := store.NewInMemoryStore()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	memoryStore.SaveResult("https://rendered.com", resultAt(start, 500, 100*time.Millisecond))
+	memoryStore.SaveResult("https://rendered.com", resultAt(start.Add(90*time.Second), 200, 100*time.Millisecond))
+	memoryStore.SaveResult("https://rendered.com", resultAt(start.Add(180*time.Second), 200, 100*time.Millisecond))
+
+	view.NewCLIView(settings).RenderMetrics(memoryStore.GetMetrics())
+
+	rows := tests.ParseLinesForURL(output.String(), "https://rendered.com")
+	require.Len(t, rows, 1)
+	assert.Equal(t, []string{"1", "1m30s", "1m30s", "1m30s", "2 UP"}, rows[0][9:])
+}
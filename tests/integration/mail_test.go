@@ -0,0 +1,180 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/tests"
+	"crypto/tls"
+	"net"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMailServer speaks just enough SMTP, IMAP or FTP for the checks. Without a certificate
+// STARTTLS is not offered, with implicitTLS the connection is encrypted right away.
+func fakeMailServer(protocol string, certificate *tls.Certificate, implicitTLS bool) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		startTLS := func() bool {
+			tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{*certificate}})
+			conn = tlsConn
+			return tlsConn.Handshake() == nil
+		}
+		if implicitTLS && !startTLS() {
+			return
+		}
+		text := textproto.NewConn(conn)
+		switch protocol {
+		case "smtp":
+			_ = text.PrintfLine("220-mail.example.com ESMTP Postfix")
+			_ = text.PrintfLine("220 no UCE")
+		case "imap":
+			_ = text.PrintfLine("* OK [CAPABILITY IMAP4rev1 STARTTLS] Dovecot ready.")
+		case "ftp":
+			_ = text.PrintfLine("220 (vsFTPd 3.0.5)")
+		}
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			tag, command, _ := strings.Cut(line, " ")
+			if protocol != "imap" {
+				command = line
+			}
+			switch {
+			case strings.HasPrefix(command, "EHLO "):
+				_ = text.PrintfLine("250-mail.example.com")
+				if certificate != nil {
+					_ = text.PrintfLine("250-STARTTLS")
+				}
+				_ = text.PrintfLine("250 8BITMIME")
+			case command == "STARTTLS" && protocol == "smtp":
+				_ = text.PrintfLine("220 2.0.0 Ready to start TLS")
+			case command == "STARTTLS":
+				_ = text.PrintfLine("%s OK Begin TLS negotiation now.", tag)
+			case command == "AUTH TLS":
+				_ = text.PrintfLine("234 Proceed with negotiation.")
+			case command == "QUIT" && protocol == "smtp":
+				_ = text.PrintfLine("221 2.0.0 Bye")
+				return
+			case command == "QUIT":
+				_ = text.PrintfLine("221 Goodbye.")
+				return
+			case command == "LOGOUT":
+				_ = text.PrintfLine("* BYE Logging out")
+				_ = text.PrintfLine("%s OK Logout completed.", tag)
+				return
+			default:
+				_ = text.PrintfLine("502 5.5.2 Error: command not recognized")
+				continue
+			}
+			if strings.Contains(command, "TLS") {
+				if certificate == nil || !startTLS() {
+					return
+				}
+				text = textproto.NewConn(conn)
+			}
+		}
+	}
+}
+
+// testCertificate returns the certificate of the httptest TLS servers and its expiry.
+func testCertificate() (*tls.Certificate, time.Time) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	return &server.TLS.Certificates[0], server.Certificate().NotAfter
+}
+
+func phaseNames(phases []model.Phase) []string {
+	names := make([]string, 0, len(phases))
+	for _, phase := range phases {
+		names = append(names, phase.Name)
+	}
+	return names
+}
+
+func TestSMTPBannerAndStartTLS(t *testing.T) {
+	t.Parallel()
+	certificate, expiry := testCertificate()
+	relay := startTCPServer(t, fakeMailServer("smtp", certificate, false))
+	plain := startTCPServer(t, fakeMailServer("smtp", nil, false))
+	submission := startTCPServer(t, fakeMailServer("smtp", certificate, true))
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	mailService := service.NewMailService(settings)
+
+	result, err := mailService.CheckUrl("smtp://" + plain + "?expect=ESMTP")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Equal(t, []string{"connect", "banner", "ehlo", "quit"}, phaseNames(result.Phases))
+	assert.True(t, result.CertExpiry.IsZero())
+
+	result, err = mailService.CheckUrl("smtp://" + relay + "?starttls=true&insecure_skip_verify=true&helo=monitor.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "banner", "ehlo", "starttls", "quit"}, phaseNames(result.Phases))
+	assert.Equal(t, expiry, result.CertExpiry)
+	for _, phase := range result.Phases {
+		assert.Positive(t, phase.Duration, phase.Name)
+	}
+
+	result, err = mailService.CheckUrl("smtps://" + submission + "?insecure_skip_verify=true")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "tls", "banner", "ehlo", "quit"}, phaseNames(result.Phases))
+	assert.Equal(t, expiry, result.CertExpiry)
+
+	rejecting := startTCPServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("554 5.3.2 mail.example.com service currently unavailable\r\n"))
+	})
+	failing := map[string]string{
+		"smtp://" + relay + "?starttls=true":         "certificate",
+		"smtp://" + plain + "?starttls=true":         "STARTTLS is not offered",
+		"smtp://" + plain + "?expect=Exim":           `expected "Exim" in the banner`,
+		"smtp://" + rejecting:                        "unexpected greeting: 554 5.3.2 mail.example.com service currently unavailable",
+		"smtp://" + closedPort(t):                    "connection refused",
+		"smtps://" + plain + "?insecure_skip_verify": "TLS handshake failed",
+	}
+	for url, message := range failing {
+		result, err := mailService.CheckUrl(url)
+		require.Error(t, err, url)
+		assert.False(t, result.IsOk, url)
+		assert.Contains(t, result.ErrorMessage(), message, url)
+	}
+
+	assert.NoError(t, defaultRegistry.ValidateURL("smtp://relay.example.com?starttls=true"))
+}
+
+func TestIMAPAndFTPStartTLS(t *testing.T) {
+	t.Parallel()
+	certificate, expiry := testCertificate()
+	imap := startTCPServer(t, fakeMailServer("imap", certificate, false))
+	imaps := startTCPServer(t, fakeMailServer("imap", certificate, true))
+	ftp := startTCPServer(t, fakeMailServer("ftp", certificate, false))
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	mailService := service.NewMailService(settings)
+
+	result, err := mailService.CheckUrl("imap://" + imap + "?starttls=true&insecure_skip_verify=true&expect=Dovecot")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "banner", "starttls", "quit"}, phaseNames(result.Phases))
+	assert.Equal(t, expiry, result.CertExpiry)
+
+	result, err = mailService.CheckUrl("imaps://" + imaps + "?insecure_skip_verify=true")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "tls", "banner", "quit"}, phaseNames(result.Phases))
+
+	result, err = mailService.CheckUrl("ftp://" + ftp + "?starttls=true&insecure_skip_verify=true&expect=vsFTPd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"connect", "banner", "starttls", "quit"}, phaseNames(result.Phases))
+	assert.Equal(t, expiry, result.CertExpiry)
+
+	_, err = mailService.CheckUrl("ftp://" + ftp + "?starttls=true")
+	assert.ErrorContains(t, err, "certificate")
+	_, err = mailService.CheckUrl("imap://" + ftp)
+	assert.ErrorContains(t, err, "unexpected greeting: 220 (vsFTPd 3.0.5)")
+}
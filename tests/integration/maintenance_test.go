@@ -0,0 +1,293 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/api"
+	"GoHealthChecker/internal/config"
+	"GoHealthChecker/internal/controller"
+	"GoHealthChecker/internal/maintenance"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/internal/store"
+	"GoHealthChecker/internal/view"
+	"GoHealthChecker/tests"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceWindowsFromConfig(t *testing.T) {
+	t.Parallel()
+	appConfig, err := config.Parse([]byte(`{
+		"targets": [
+			{"url": "https://api.com", "tags": ["backend"]},
+			{"url": "https://web.com", "tags": ["frontend"]}
+		],
+		"maintenance": [
+			{"name": "weekly", "tags": ["backend"], "schedule": "0 2 * * 0", "duration": "2h"},
+			{"name": "migration", "targets": ["https://web.com"], "start": "2025-01-06T10:00:00Z", "end": "2025-01-06T11:00:00Z"}
+		]
+	}`))
+	require.NoError(t, err)
+	manager, err := appConfig.NewMaintenance()
+	require.NoError(t, err)
+
+	// 2025-01-05 is a Sunday
+	sunday := time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC)
+	cases := []struct {
+		url      string
+		at       time.Time
+		expected bool
+	}{
+		{"https://api.com", sunday, true},
+		{"https://api.com", sunday.Add(119 * time.Minute), true},
+		{"https://api.com", sunday.Add(2 * time.Hour), false},
+		{"https://api.com", sunday.Add(-time.Minute), false},
+		{"https://api.com", sunday.Add(24 * time.Hour), false},
+		{"https://web.com", sunday, false},
+		{"https://web.com", time.Date(2025, 1, 6, 10, 30, 0, 0, time.UTC), true},
+		{"https://web.com", time.Date(2025, 1, 6, 11, 0, 0, 0, time.UTC), false},
+	}
+	for _, c := range cases {
+		active, _ := manager.InMaintenance(c.url, c.at)
+		assert.Equal(t, c.expected, active, "%s at %s", c.url, c.at)
+	}
+	_, reason := manager.InMaintenance("https://api.com", sunday)
+	assert.Equal(t, "window weekly", reason)
+
+	schedule, err := maintenance.ParseSchedule("*/15 9-17 * * 1-5")
+	require.NoError(t, err)
+	assert.True(t, schedule.Matches(time.Date(2025, 1, 6, 9, 45, 0, 0, time.UTC)))
+	assert.False(t, schedule.Matches(time.Date(2025, 1, 6, 9, 50, 0, 0, time.UTC)))
+	assert.False(t, schedule.Matches(time.Date(2025, 1, 5, 9, 45, 0, 0, time.UTC)))
+
+	for _, invalid := range []string{
+		`{"maintenance": [{"name": "a", "targets": ["*"], "schedule": "0 2 * *", "duration": "1h"}]}`,
+		`{"maintenance": [{"name": "a", "targets": ["*"], "schedule": "61 2 * * *", "duration": "1h"}]}`,
+		`{"maintenance": [{"name": "a", "targets": ["*"], "schedule": "0 2 * * *"}]}`,
+		`{"maintenance": [{"name": "a", "schedule": "0 2 * * *", "duration": "1h"}]}`,
+		`{"maintenance": [{"name": "a", "targets": ["*"], "start": "2025-01-06T11:00:00Z", "end": "2025-01-06T10:00:00Z"}]}`,
+	} {
+		_, err := config.Parse([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestScheduleLastTrigger(t *testing.T) {
+	t.Parallel()
+	// Stepping back minute by minute, the way cron would see it
+	lastTriggerByMinute := func(schedule *maintenance.Schedule, at time.Time, lookback time.Duration) (time.Time, bool) {
+		for minute := at.Truncate(time.Minute); !minute.Before(at.Add(-lookback)); minute = minute.Add(-time.Minute) {
+			if schedule.Matches(minute) {
+				return minute, true
+			}
+		}
+		return time.Time{}, false
+	}
+	start := time.Date(2025, 1, 5, 13, 37, 30, 0, time.UTC)
+	for _, spec := range []string{"0 2 * * 0", "*/15 9-17 * * 1-5", "30 23 31 * *", "5,55 * 1 1 *", "0 0 13 * 5", "* * * * *"} {
+		schedule, err := maintenance.ParseSchedule(spec)
+		require.NoError(t, err)
+		for offset := time.Duration(0); offset < 40*24*time.Hour; offset += 17*time.Hour + 23*time.Minute {
+			at := start.Add(offset)
+			for _, lookback := range []time.Duration{0, time.Minute, 2 * time.Hour, 3 * 24 * time.Hour, 35 * 24 * time.Hour} {
+				expected, expectedOk := lastTriggerByMinute(schedule, at, lookback)
+				actual, ok := schedule.LastTrigger(at, lookback)
+				assert.Equal(t, expectedOk, ok, "%s at %s looking back %s", spec, at, lookback)
+				assert.Equal(t, expected, actual, "%s at %s looking back %s", spec, at, lookback)
+			}
+		}
+	}
+}
+
+func TestSilencesThroughControlAPI(t *testing.T) {
+	t.Parallel()
+	manager := maintenance.NewManager(nil, map[string][]string{"https://tagged.com": {"deploy"}})
+	server := httptest.NewServer(api.NewServer(manager).Handler())
+	defer server.Close()
+	client := api.NewClient(server.URL)
+
+	silence, err := client.AddSilence(api.SilenceRequest{Targets: []string{"https://silenced.com"}, Duration: "1h", Comment: "deploy 1.2"})
+	require.NoError(t, err)
+	assert.Equal(t, "1", silence.ID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), silence.End, time.Minute)
+	_, err = client.AddSilence(api.SilenceRequest{Tags: []string{"deploy"}, Duration: "30m"})
+	require.NoError(t, err)
+
+	silences, err := client.Silences()
+	require.NoError(t, err)
+	require.Len(t, silences, 2)
+	assert.Equal(t, "deploy 1.2", silences[0].Comment)
+
+	active, reason := manager.InMaintenance("https://silenced.com", time.Now())
+	assert.True(t, active)
+	assert.Equal(t, "silence 1", reason)
+	active, _ = manager.InMaintenance("https://tagged.com", time.Now())
+	assert.True(t, active)
+	active, _ = manager.InMaintenance("https://other.com", time.Now())
+	assert.False(t, active)
+
+	require.NoError(t, client.RemoveSilence("1"))
+	active, _ = manager.InMaintenance("https://silenced.com", time.Now())
+	assert.False(t, active)
+
+	err = client.RemoveSilence("1")
+	assert.ErrorContains(t, err, "no silence 1")
+	_, err = client.AddSilence(api.SilenceRequest{Duration: "1h"})
+	assert.ErrorContains(t, err, "requires targets or tags")
+	_, err = client.AddSilence(api.SilenceRequest{Targets: []string{"https://x.com"}, Duration: "soon"})
+	assert.ErrorContains(t, err, "invalid duration")
+
+	// Expired silences are forgotten without listing them
+	expired, err := manager.AddSilence(maintenance.Silence{Targets: []string{"https://x.com"}, Start: time.Now().Add(-time.Hour), End: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = manager.AddSilence(maintenance.Silence{Targets: []string{"https://y.com"}, End: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.False(t, manager.RemoveSilence(expired.ID))
+	ending, err := manager.AddSilence(maintenance.Silence{Targets: []string{"https://z.com"}, End: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	active, _ = manager.InMaintenance("https://other.com", time.Now().Add(2*time.Hour))
+	assert.False(t, active)
+	assert.False(t, manager.RemoveSilence(ending.ID))
+}
+
+func TestMaintenanceExcludedFromUptimeAndIncidents(t *testing.T) {
+	t.Parallel()
+	output, _, _, settings := tests.CreateConfiguration(1, 1)
+	memoryStore := store.NewInMemoryStore()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	url := "https://deployed.com"
+	memoryStore.SaveResult(url, resultAt(start, 200, 100*time.Millisecond))
+	for i := 1; i <= 3; i++ {
+		failure := resultAt(start.Add(time.Duration(i)*time.Minute), 503, 5*time.Second)
+		failure.Maintenance = true
+		memoryStore.SaveResult(url, failure)
+	}
+	memoryStore.SaveResult(url, resultAt(start.Add(4*time.Minute), 200, 300*time.Millisecond))
+
+	metrics := memoryStore.GetMetrics()[url]
+	assert.Equal(t, 2, metrics.TotalRequests)
+	assert.Equal(t, 0, metrics.FailedRequests)
+	assert.Equal(t, 3, metrics.MaintenanceRequests)
+	assert.Equal(t, 200.0, metrics.LatencyAverage)
+	assert.Empty(t, memoryStore.GetIncidents(url))
+
+	latest := resultAt(start.Add(5*time.Minute), 503, 0)
+	latest.Maintenance = true
+	memoryStore.SaveResult(url, latest)
+	view.NewCLIView(settings).Render(memoryStore.GetLatestResults())
+	rows := tests.ParseLinesForURL(output.String(), url)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "MAINT", rows[0][1])
+
+	// Targets checked only under maintenance have no uptime
+	maintained := "https://maintained.com"
+	memoryStore.SaveResult(maintained, latest)
+	output.Reset()
+	view.NewCLIView(settings).RenderMetrics(memoryStore.GetMetrics())
+	rows = tests.ParseLinesForURL(output.String(), maintained)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "MAINT", rows[0][2])
+	assert.NotContains(t, output.String(), "NaN")
+}
+
+func TestControllerMutesNotificationsDuringSilence(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	httpmockTransport := httpmock.NewMockTransport()
+	httpmockTransport.RegisterResponder("GET", "https://deploying.com", httpmock.NewStringResponder(503, "deploying"))
+	httpmockTransport.RegisterResponder("GET", "https://broken.com", httpmock.NewStringResponder(500, "broken"))
+
+	manager := maintenance.NewManager(nil, nil)
+	_, err := manager.AddSilence(maintenance.Silence{Targets: []string{"https://deploying.com"}, End: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	_, _, cancel, settings := tests.CreateConfiguration(1, 1)
+	settings.WithPollingInterval(100 * time.Millisecond)
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	dispatcher.WithMute(func(event notifier.Event) (bool, string) {
+		return manager.InMaintenance(event.Target, event.Timestamp)
+	})
+	resultStore := store.NewInMemoryStore()
+	appController := controller.NewController(
+		resultStore,
+		view.NewCLIView(settings),
+		service.NewHTTPServiceWithTransport(httpmockTransport, settings),
+		settings,
+	).WithDispatcher(dispatcher).WithMaintenance(manager)
+
+	done := make(chan struct{})
+	go func() {
+		_ = appController.Start([]string{"https://deploying.com", "https://broken.com"})
+		close(done)
+	}()
+	time.Sleep(350 * time.Millisecond)
+	cancel()
+	<-done
+	dispatcher.Close()
+
+	// Both went DOWN right away, only the target without a silence is notified
+	payloads := receiver.received()
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "https://broken.com", payloads[0]["target"])
+	for _, result := range resultStore.GetHistory("https://deploying.com", time.Time{}, time.Time{}) {
+		assert.True(t, result.Maintenance)
+		assert.Equal(t, model.StateDown, result.State)
+	}
+	assert.Equal(t, 0, resultStore.GetMetrics()["https://deploying.com"].TotalRequests)
+}
+
+func TestControllerNotifiesOutageOutlastingSilence(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	httpmockTransport := httpmock.NewMockTransport()
+	httpmockTransport.RegisterResponder("GET", "https://deploying.com", httpmock.NewStringResponder(503, "deploying"))
+
+	manager := maintenance.NewManager(nil, nil)
+	silence, err := manager.AddSilence(maintenance.Silence{Targets: []string{"https://deploying.com"}, End: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	_, _, cancel, settings := tests.CreateConfiguration(1, 1)
+	settings.WithPollingInterval(100 * time.Millisecond)
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	dispatcher.WithMute(func(event notifier.Event) (bool, string) {
+		return manager.InMaintenance(event.Target, event.Timestamp)
+	})
+	appController := controller.NewController(
+		store.NewInMemoryStore(),
+		view.NewCLIView(settings),
+		service.NewHTTPServiceWithTransport(httpmockTransport, settings),
+		settings,
+	).WithDispatcher(dispatcher).WithMaintenance(manager)
+
+	done := make(chan struct{})
+	go func() {
+		_ = appController.Start([]string{"https://deploying.com"})
+		close(done)
+	}()
+	time.Sleep(250 * time.Millisecond)
+	assert.Empty(t, receiver.received())
+	// The target is still DOWN when the silence ends
+	require.True(t, manager.RemoveSilence(silence.ID))
+	time.Sleep(250 * time.Millisecond)
+	cancel()
+	<-done
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "https://deploying.com", payloads[0]["target"])
+	assert.Equal(t, "UNKNOWN", payloads[0]["old_state"])
+	assert.Equal(t, "DOWN", payloads[0]["new_state"])
+}
@@ -0,0 +1,244 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/config"
+	"GoHealthChecker/internal/controller"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/internal/store"
+	"GoHealthChecker/internal/view"
+	"GoHealthChecker/tests"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver records the JSON bodies POSTed to it.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	payloads []map[string]any
+	headers  []http.Header
+	paths    []string
+}
+
+func (r *webhookReceiver) handler(status func() int) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		payload := make(map[string]any)
+		_ = json.Unmarshal(body, &payload)
+		r.mu.Lock()
+		r.payloads = append(r.payloads, payload)
+		r.headers = append(r.headers, req.Header.Clone())
+		r.paths = append(r.paths, req.URL.RequestURI())
+		r.mu.Unlock()
+		w.WriteHeader(status())
+	}
+}
+
+func (r *webhookReceiver) received() []map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]any{}, r.payloads...)
+}
+
+func fastRetries() notifier.Options {
+	options := notifier.DefaultOptions()
+	options.RetryBackoff = 10 * time.Millisecond
+	options.Timeout = 200 * time.Millisecond
+	return options
+}
+
+func TestWebhookDeliversStateChange(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{
+		notifier.NewWebhookNotifier(server.URL, map[string]string{"Authorization": "Bearer secret"}),
+	}, fastRetries())
+	timestamp := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	dispatcher.Dispatch(notifier.Event{
+		Target:           "https://webhook.com",
+		OldState:         model.StateDown,
+		NewState:         model.StateUp,
+		StatusCode:       200,
+		Latency:          150 * time.Millisecond,
+		Timestamp:        timestamp,
+		IncidentDuration: 90 * time.Second,
+	})
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "https://webhook.com", payloads[0]["target"])
+	assert.Equal(t, "DOWN", payloads[0]["old_state"])
+	assert.Equal(t, "UP", payloads[0]["new_state"])
+	assert.Equal(t, 150.0, payloads[0]["latency_ms"])
+	assert.Equal(t, 90.0, payloads[0]["incident_duration_seconds"])
+	assert.Equal(t, "2025-01-01T12:00:00Z", payloads[0]["timestamp"])
+	assert.Equal(t, "Bearer secret", receiver.headers[0].Get("Authorization"))
+
+	deliveries := dispatcher.Deliveries()
+	require.Len(t, deliveries, 1)
+	assert.True(t, deliveries[0].Succeeded())
+	assert.Equal(t, 1, deliveries[0].Attempts)
+}
+
+func TestWebhookRetriesFailedDeliveries(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int {
+		if calls.Add(1) <= 2 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusAccepted
+	}))
+	defer server.Close()
+
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	dispatcher.Dispatch(notifier.Event{Target: "https://retry.com", OldState: model.StateUp, NewState: model.StateDown})
+	dispatcher.Close()
+
+	deliveries := dispatcher.Deliveries()
+	require.Len(t, deliveries, 1)
+	assert.True(t, deliveries[0].Succeeded())
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Len(t, receiver.received(), 3)
+}
+
+func TestWebhookGivesUpAfterTimeouts(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	options := fastRetries()
+	options.Retries = 1
+	options.Timeout = 50 * time.Millisecond
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, options)
+	dispatcher.Dispatch(notifier.Event{Target: "https://timeout.com", OldState: model.StateUp, NewState: model.StateDown})
+	dispatcher.Close()
+
+	deliveries := dispatcher.Deliveries()
+	require.Len(t, deliveries, 1)
+	assert.False(t, deliveries[0].Succeeded())
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Contains(t, deliveries[0].Error, "deadline exceeded")
+}
+
+func TestControllerNotifiesOnStateChanges(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	// UP, DOWN, DOWN, UP, UP, ...
+	var calls atomic.Int32
+	httpmockTransport := httpmock.NewMockTransport()
+	httpmockTransport.RegisterResponder("GET", "https://notified.com", func(req *http.Request) (*http.Response, error) {
+		switch calls.Add(1) {
+		case 2, 3:
+			return httpmock.NewStringResponse(503, "unavailable"), nil
+		default:
+			return httpmock.NewStringResponse(200, "ok"), nil
+		}
+	})
+
+	_, _, cancel, settings := tests.CreateConfiguration(1, 1)
+	settings.WithPollingInterval(100 * time.Millisecond)
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	appController := controller.NewController(
+		store.NewInMemoryStore(),
+		view.NewCLIView(settings),
+		service.NewHTTPServiceWithTransport(httpmockTransport, settings),
+		settings,
+	).WithDispatcher(dispatcher)
+
+	done := make(chan struct{})
+	go func() {
+		_ = appController.Start([]string{"https://notified.com"})
+		close(done)
+	}()
+	time.Sleep(750 * time.Millisecond)
+	cancel()
+	<-done
+	dispatcher.Close()
+
+	// The initial UNKNOWN -> UP is not notified
+	payloads := receiver.received()
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "DOWN", payloads[0]["new_state"])
+	assert.Equal(t, "HTTP 503", payloads[0]["error"])
+	assert.Equal(t, "UP", payloads[1]["new_state"])
+	assert.InDelta(t, 0.2, payloads[1]["incident_duration_seconds"], 0.1)
+}
+
+func TestSlackAndTeamsMessages(t *testing.T) {
+	t.Parallel()
+	slackReceiver := &webhookReceiver{}
+	slackServer := httptest.NewServer(slackReceiver.handler(func() int { return http.StatusOK }))
+	defer slackServer.Close()
+	teamsReceiver := &webhookReceiver{}
+	teamsServer := httptest.NewServer(teamsReceiver.handler(func() int { return http.StatusOK }))
+	defer teamsServer.Close()
+
+	appConfig, err := config.Parse([]byte(`{"notifiers": [
+		{"type": "slack", "url": "` + slackServer.URL + `"},
+		{"type": "webhook", "format": "teams", "url": "` + teamsServer.URL + `"}
+	]}`))
+	require.NoError(t, err)
+	notifiers, err := appConfig.NewNotifiers()
+	require.NoError(t, err)
+	require.Len(t, notifiers, 2)
+	assert.Equal(t, "webhook(slack) "+slackServer.URL, notifiers[0].Name())
+
+	dispatcher := notifier.NewDispatcher(notifiers, fastRetries())
+	dispatcher.Dispatch(notifier.Event{
+		Target:    "https://chat.com",
+		OldState:  model.StateUp,
+		NewState:  model.StateDown,
+		Error:     "connection refused",
+		Latency:   20 * time.Millisecond,
+		Timestamp: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	})
+	dispatcher.Close()
+
+	slack := slackReceiver.received()
+	require.Len(t, slack, 1)
+	assert.Equal(t, "https://chat.com is DOWN: connection refused", slack[0]["text"])
+	attachment := slack[0]["attachments"].([]any)[0].(map[string]any)
+	assert.Equal(t, "#D50200", attachment["color"])
+	blocks := attachment["blocks"].([]any)
+	title := blocks[0].(map[string]any)["text"].(map[string]any)["text"]
+	assert.Equal(t, "*<https://chat.com|https://chat.com>* is *DOWN*", title)
+	lastError := blocks[2].(map[string]any)["text"].(map[string]any)["text"]
+	assert.Contains(t, lastError, "connection refused")
+
+	teams := teamsReceiver.received()
+	require.Len(t, teams, 1)
+	assert.Equal(t, "MessageCard", teams[0]["@type"])
+	assert.Equal(t, "D50200", teams[0]["themeColor"])
+	facts := teams[0]["sections"].([]any)[0].(map[string]any)["facts"].([]any)
+	assert.Contains(t, facts, map[string]any{"name": "Last error", "value": "connection refused"})
+	action := teams[0]["potentialAction"].([]any)[0].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"os": "default", "uri": "https://chat.com"}}, action["targets"])
+
+	_, err = notifier.NewFormatter("xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/config"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recoveryEvent(target string) notifier.Event {
+	return notifier.Event{
+		Target:           target,
+		OldState:         model.StateDown,
+		NewState:         model.StateUp,
+		StatusCode:       200,
+		Timestamp:        time.Date(2025, 1, 1, 12, 5, 0, 0, time.UTC),
+		IncidentDuration: 5 * time.Minute,
+	}
+}
+
+func TestPagerDutyTriggerAndResolveShareDedupKey(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusAccepted }))
+	defer server.Close()
+
+	appConfig, err := config.Parse([]byte(`{"notifiers": [
+		{"type": "pagerduty", "url": "` + server.URL + `/v2/enqueue", "routing_key": "R0UT1NG", "severity": "error"}
+	]}`))
+	require.NoError(t, err)
+	notifiers, err := appConfig.NewNotifiers()
+	require.NoError(t, err)
+
+	dispatcher := notifier.NewDispatcher(notifiers, fastRetries())
+	dispatcher.Dispatch(downEvent("https://paged.com", "HTTP 503"))
+	dispatcher.Dispatch(recoveryEvent("https://paged.com"))
+	dispatcher.Dispatch(downEvent("https://other.com", "HTTP 503"))
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 3)
+	assert.Equal(t, "/v2/enqueue", receiver.paths[0])
+
+	trigger := payloads[0]
+	assert.Equal(t, "R0UT1NG", trigger["routing_key"])
+	assert.Equal(t, "trigger", trigger["event_action"])
+	payload := trigger["payload"].(map[string]any)
+	assert.Equal(t, "https://paged.com is DOWN: HTTP 503", payload["summary"])
+	assert.Equal(t, "https://paged.com", payload["source"])
+	assert.Equal(t, "error", payload["severity"])
+	assert.Equal(t, "HTTP 503", payload["custom_details"].(map[string]any)["error"])
+
+	resolve := payloads[1]
+	assert.Equal(t, "resolve", resolve["event_action"])
+	assert.Nil(t, resolve["payload"])
+	assert.Equal(t, trigger["dedup_key"], resolve["dedup_key"])
+	assert.Equal(t, notifier.DedupKey("https://paged.com"), resolve["dedup_key"])
+	assert.NotEqual(t, trigger["dedup_key"], payloads[2]["dedup_key"])
+}
+
+func TestOpsgenieCreatesAndClosesAlertByAlias(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusAccepted }))
+	defer server.Close()
+
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{
+		notifier.NewOpsgenieNotifier(server.URL+"/", "k3y", ""),
+	}, fastRetries())
+	dispatcher.Dispatch(downEvent("https://genie.com", "connection refused"))
+	dispatcher.Dispatch(recoveryEvent("https://genie.com"))
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 2)
+	alias := notifier.DedupKey("https://genie.com")
+
+	assert.Equal(t, "/v2/alerts", receiver.paths[0])
+	assert.Equal(t, "GenieKey k3y", receiver.headers[0].Get("Authorization"))
+	assert.Equal(t, alias, payloads[0]["alias"])
+	assert.Equal(t, "P1", payloads[0]["priority"])
+	assert.Equal(t, "https://genie.com is DOWN: connection refused", payloads[0]["message"])
+
+	assert.Equal(t, "/v2/alerts/"+alias+"/close?identifierType=alias", receiver.paths[1])
+	assert.Equal(t, "GenieKey k3y", receiver.headers[1].Get("Authorization"))
+	assert.Equal(t, "GoHealthChecker", payloads[1]["source"])
+}
+
+func TestOpsgenieTruncatesLongMessages(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusAccepted }))
+	defer server.Close()
+
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{
+		notifier.NewOpsgenieNotifier(server.URL+"/", "k3y", ""),
+	}, fastRetries())
+	dispatcher.Dispatch(downEvent("https://genie.com", strings.Repeat("služba nedostupná ", 10)))
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 1)
+	message := payloads[0]["message"].(string)
+	assert.True(t, utf8.ValidString(message))
+	assert.Equal(t, 130, utf8.RuneCountInString(message))
+	assert.True(t, strings.HasSuffix(message, "..."))
+}
+
+func TestPagingResolvesWhenTargetLeavesDown(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusAccepted }))
+	defer server.Close()
+
+	stateChange := func(from, to model.State) notifier.Event {
+		event := recoveryEvent("https://wobbly.com")
+		event.OldState, event.NewState = from, to
+		return event
+	}
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{
+		notifier.NewPagerDutyNotifier(server.URL+"/pagerduty", "R0UT1NG", ""),
+		notifier.NewOpsgenieNotifier(server.URL+"/opsgenie", "k3y", ""),
+	}, fastRetries())
+	// A warning of a healthy target pages nobody
+	dispatcher.Dispatch(stateChange(model.StateUp, model.StateDegraded))
+	dispatcher.Dispatch(stateChange(model.StateDegraded, model.StateDown))
+	dispatcher.Dispatch(stateChange(model.StateDown, model.StateDegraded))
+	dispatcher.Dispatch(stateChange(model.StateDegraded, model.StateUp))
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 4)
+	actions := map[string][]string{}
+	for i, path := range receiver.paths {
+		switch {
+		case path == "/pagerduty":
+			actions["pagerduty"] = append(actions["pagerduty"], payloads[i]["event_action"].(string))
+		case strings.HasSuffix(path, "/close?identifierType=alias"):
+			actions["opsgenie"] = append(actions["opsgenie"], "close")
+		default:
+			actions["opsgenie"] = append(actions["opsgenie"], "create")
+		}
+	}
+	assert.Equal(t, []string{"trigger", "resolve"}, actions["pagerduty"])
+	assert.Equal(t, []string{"create", "close"}, actions["opsgenie"])
+}
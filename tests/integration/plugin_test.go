@@ -0,0 +1,158 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/controller"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/internal/state"
+	"GoHealthChecker/internal/store"
+	"GoHealthChecker/internal/view"
+	"GoHealthChecker/tests"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pluginScript writes a Nagios plugin exiting with its first argument.
+func pluginScript(t *testing.T) string {
+	return writeScript(t, `
+case "$1" in
+  0) echo "DISK OK - free space: / 3326 MB (56%) | /=2643MB;5948;5958;0;5968" ;;
+  1) echo "DISK WARNING - free space: / 600 MB (10%)"
+     echo "/boot is fine too"
+     echo "| 'inode used'=53%;80;90 /boot=68MB;88;93;0;98" ;;
+  2) echo "DISK CRITICAL - free space: / 50 MB (1%) | /=5950MB;5948;5958;0;5968" ;;
+  sleep) sleep 5 ;;
+  *) echo "Usage: check_disk -w limit -c limit" ;;
+esac
+exit "$1"
+`)
+}
+
+func TestExecPluginExitCodes(t *testing.T) {
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = 500 * time.Millisecond
+	execService := service.NewExecService(settings)
+	plugin := "exec://" + pluginScript(t)
+
+	result, err := execService.CheckUrl(plugin + " 0")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Empty(t, result.CheckState)
+	assert.Equal(t, "DISK OK - free space: / 3326 MB (56%)", result.Output)
+	assert.Equal(t, []model.Perfdata{{Label: "/", Value: 2643, Unit: "MB", Warning: "5948", Critical: "5958", Min: "0", Max: "5968"}}, result.Perfdata)
+
+	result, err = execService.CheckUrl(plugin + " 1")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Equal(t, model.StateDegraded, result.CheckState)
+	assert.Equal(t, "DISK WARNING - free space: / 600 MB (10%)", result.Output)
+	require.Len(t, result.Perfdata, 2)
+	assert.Equal(t, model.Perfdata{Label: "inode used", Value: 53, Unit: "%", Warning: "80", Critical: "90"}, result.Perfdata[0])
+	assert.Equal(t, "/boot", result.Perfdata[1].Label)
+
+	result, err = execService.CheckUrl(plugin + " 2")
+	require.Error(t, err)
+	assert.False(t, result.IsOk)
+	assert.Empty(t, result.CheckState)
+	assert.Equal(t, "DISK CRITICAL - free space: / 50 MB (1%)", result.ErrorMessage())
+	assert.Len(t, result.Perfdata, 1)
+
+	unknown := map[string]string{
+		plugin + " 3":                   "Usage: check_disk -w limit -c limit",
+		"exec:///nonexistent/check_foo": "no such file or directory",
+		"exec://" + `"unterminated`:     "unterminated quote",
+	}
+	for url, message := range unknown {
+		result, err := execService.CheckUrl(url)
+		require.Error(t, err, url)
+		assert.False(t, result.IsOk, url)
+		assert.Equal(t, model.StateUnknown, result.CheckState, url)
+		assert.Contains(t, result.ErrorMessage(), message, url)
+	}
+
+	// Timeouts are CRITICAL, like in Nagios
+	result, err = execService.CheckUrl(plugin + " sleep")
+	require.Error(t, err)
+	assert.Contains(t, result.ErrorMessage(), "plugin timed out after 500ms")
+	assert.Empty(t, result.CheckState)
+
+	assert.NoError(t, defaultRegistry.ValidateURL("exec:///usr/lib/nagios/plugins/check_disk -w 10% -c 5% -p /"))
+	assert.Error(t, defaultRegistry.ValidateURL("exec://"))
+}
+
+func TestExecPluginStatesInView(t *testing.T) {
+	output, _, cancel, settings := tests.CreateConfiguration(1, 1)
+	settings.WithPollingInterval(100 * time.Millisecond)
+	settings.Timeout = time.Second
+	plugin := "exec://" + pluginScript(t)
+	checker := service.NewRegistry(service.NewHTTPService(settings)).WithScheme("exec", service.NewExecService(settings))
+
+	resultStore := store.NewInMemoryStore()
+	appController := controller.NewController(resultStore, view.NewCLIView(settings), checker, settings)
+	done := make(chan struct{})
+	go func() {
+		_ = appController.Start([]string{plugin + " 1", plugin + " 3"})
+		close(done)
+	}()
+	time.Sleep(350 * time.Millisecond)
+	cancel()
+	<-done
+
+	latest := resultStore.GetLatestResults()
+	assert.Equal(t, model.StateDegraded, latest[plugin+" 1"].State)
+	assert.Equal(t, model.StateUnknown, latest[plugin+" 3"].State)
+	// Inconclusive checks are no outage
+	metrics := resultStore.GetMetrics()[plugin+" 3"]
+	assert.Zero(t, metrics.TotalRequests)
+	assert.Positive(t, metrics.UnknownRequests)
+	assert.Empty(t, resultStore.GetIncidents(plugin+" 3"))
+	for url, state := range map[string]string{plugin + " 1": "DEGRADED", plugin + " 3": "UNKNOWN"} {
+		rows := tests.ParseLinesForURL(output.String(), url)
+		require.NotEmpty(t, rows, url)
+		assert.Equal(t, state, rows[0][1], url)
+	}
+}
+
+func TestTrackerWithPluginStates(t *testing.T) {
+	t.Parallel()
+	settings := model.NewAppSettings().WithFallRise(2, 1)
+	tracker := state.NewTracker(*settings)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	url := "exec:///usr/lib/nagios/plugins/check_disk"
+
+	// Worse states need 2 consecutive results, better states 1
+	checkStates := []model.State{"", model.StateDegraded, model.StateDegraded, model.StateUnknown, model.StateUnknown, "", model.StateDown, model.StateDown}
+	expected := []model.State{
+		model.StateUp, model.StateUp, model.StateDegraded, model.StateDegraded,
+		model.StateUnknown, model.StateUp, model.StateUp, model.StateDown,
+	}
+	transitions := make([]*state.Transition, 0)
+	for i, checkState := range checkStates {
+		result := resultAt(start.Add(time.Duration(i)*time.Minute), 200, 0)
+		switch checkState {
+		case model.StateDegraded:
+			result.CheckState = checkState
+		case model.StateUnknown:
+			result.IsOk, result.CheckState = false, checkState
+		case model.StateDown:
+			result.IsOk = false
+		}
+		result, transition := tracker.Observe(url, result)
+		assert.Equal(t, expected[i], result.State, "check #%d", i)
+		if transition != nil {
+			transitions = append(transitions, transition)
+		}
+	}
+
+	require.Len(t, transitions, 5)
+	assert.True(t, transitions[0].First)
+	assert.Equal(t, model.StateUp, transitions[1].From)
+	assert.Equal(t, model.StateDegraded, transitions[1].To)
+	assert.Equal(t, model.StateUnknown, transitions[2].To)
+	assert.False(t, transitions[2].First)
+	assert.Equal(t, model.StateUp, transitions[3].To)
+	assert.Equal(t, model.StateDown, transitions[4].To)
+}
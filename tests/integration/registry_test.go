@@ -0,0 +1,128 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/controller"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/internal/store"
+	"GoHealthChecker/internal/view"
+	"GoHealthChecker/tests"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// defaultRegistry validates the URLs of the built-in services.
+var defaultRegistry = service.NewDefaultRegistry(*model.NewAppSettings())
+
+// queueChecker is a checker registered by a library user, for amqp://broker/?queue=name URLs.
+type queueChecker struct{}
+
+func (queueChecker) CheckUrl(target string) (model.HealthCheckResult, error) {
+	return model.NewSuccessfulResult(time.Millisecond, 0), nil
+}
+
+func (queueChecker) ValidateURL(target string) error {
+	item, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if item.Query().Get("queue") == "" {
+		return errors.New("amqp URL must have a queue")
+	}
+	return nil
+}
+
+// plainChecker is a checker without URL validation, eg. a mock of the HTTP checker.
+type plainChecker struct{}
+
+func (plainChecker) CheckUrl(target string) (model.HealthCheckResult, error) {
+	return model.NewSuccessfulResult(time.Millisecond, 0), nil
+}
+
+func TestCheckerWithoutValidation(t *testing.T) {
+	t.Parallel()
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	appController := controller.NewController(store.NewInMemoryStore(), view.NewCLIView(settings), plainChecker{}, settings)
+
+	// Checkers which don't validate their URLs get only http:// and https:// URLs
+	for _, invalid := range []string{"gopher://unsupported.com", "tcp://db.internal:5432", "https://:443/path"} {
+		assert.Error(t, appController.Start([]string{invalid}), invalid)
+	}
+
+	// Stores only require a scheme, the rest is up to the checkers
+	assert.NoError(t, store.NewInMemoryStore().AddURL("exec:///usr/lib/nagios/plugins/check_load -w 5"))
+	for _, invalid := range []string{"", "missing-scheme", "://missing-scheme", "https://"} {
+		assert.Error(t, store.NewInMemoryStore().AddURL(invalid), invalid)
+	}
+	// The deprecated validation of the stores still checks web URLs
+	assert.NoError(t, store.ValidateURL("https://example.com/health"))
+	for _, invalid := range []string{"tcp://db.internal:5432", "https://:443/path", "https://under_score.com"} {
+		assert.Error(t, store.ValidateURL(invalid), invalid)
+	}
+}
+
+func TestControllerWithDeprecatedHTTPService(t *testing.T) {
+	t.Parallel()
+	output, _, cancel, settings := tests.CreateConfiguration(1, 1)
+	settings.WithPollingInterval(100 * time.Millisecond)
+	resultStore := store.NewInMemoryStore()
+	appController := controller.NewController(resultStore, view.NewCLIView(settings), nil, settings)
+	assert.Nil(t, appController.HTTPService)
+	// Library code setting only the former field keeps working
+	appController.HTTPService = plainChecker{}
+
+	done := make(chan struct{})
+	go func() {
+		_ = appController.Start([]string{"https://legacy.com"})
+		close(done)
+	}()
+	time.Sleep(250 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Positive(t, resultStore.GetMetrics()["https://legacy.com"].TotalRequests)
+	assert.NotEmpty(t, tests.ParseLinesForURL(output.String(), "https://legacy.com"))
+}
+
+func TestRegistryWithCustomChecker(t *testing.T) {
+	t.Parallel()
+	output, _, cancel, settings := tests.CreateConfiguration(1, 1)
+	settings.WithPollingInterval(100 * time.Millisecond)
+	registry := service.NewDefaultRegistry(settings).WithScheme("AMQP", queueChecker{})
+
+	assert.Contains(t, registry.Schemes(), "amqp")
+	assert.Contains(t, registry.Schemes(), "https")
+	assert.NoError(t, registry.ValidateURL("amqp://broker.internal?queue=jobs"))
+	assert.NoError(t, registry.ValidateURL("ws://rt.example.com/feed"))
+	assert.EqualError(t, registry.ValidateURL("amqp://broker.internal"), "amqp URL must have a queue")
+	assert.EqualError(t, registry.ValidateURL("gopher://db.internal:70"), "unsupported URL scheme: gopher")
+	assert.ErrorContains(t, registry.ValidateURL("udp://syslog.internal:514?match=%28"), "invalid match")
+	assert.ErrorContains(t, registry.ValidateURL("dns://10.0.0.2/api.internal?type=BOGUS"), "BOGUS")
+	assert.ErrorContains(t, registry.ValidateURL("grpc://backend.internal:50051?metadata=token"), "invalid metadata")
+
+	// Invalid URLs fail at startup, before any check
+	resultStore := store.NewInMemoryStore()
+	appController := controller.NewController(resultStore, view.NewCLIView(settings), registry, settings)
+	err := appController.Start([]string{"https://web.com", "amqp://broker.internal"})
+	assert.ErrorContains(t, err, "amqp URL must have a queue")
+	assert.Empty(t, resultStore.GetURLs())
+
+	done := make(chan struct{})
+	go func() {
+		_ = appController.Start([]string{"amqp://broker.internal?queue=jobs"})
+		close(done)
+	}()
+	time.Sleep(250 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Positive(t, resultStore.GetMetrics()["amqp://broker.internal?queue=jobs"].TotalRequests)
+	rows := tests.ParseLinesForURL(output.String(), "amqp://broker.internal?queue=jobs")
+	require.NotEmpty(t, rows)
+	assert.Equal(t, "UP", rows[0][1])
+}
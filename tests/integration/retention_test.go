@@ -0,0 +1,235 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/store"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionDownsamplesExpiredRawResults(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	// Write raw results three days ago and today
+	fileStore, err := store.NewFileStore(dir)
+	require.NoError(t, err)
+	old := time.Now().UTC().Add(-72 * time.Hour).Truncate(time.Hour)
+	latencies := []time.Duration{100, 200, 300, 400}
+	for i, latency := range latencies {
+		result := resultAt(old.Add(time.Duration(i)*20*time.Second), 200, latency*time.Millisecond)
+		if i == 3 {
+			result = resultAt(old.Add(time.Duration(i)*20*time.Second), 500, latency*time.Millisecond)
+		}
+		fileStore.SaveResult("https://retention.com", result)
+	}
+	fileStore.SaveResult("https://retention.com", resultAt(time.Now().UTC(), 200, 50*time.Millisecond))
+	require.NoError(t, fileStore.Close())
+	oldSegment := filepath.Join(dir, "results-"+old.Format("2006-01-02")+".jsonl")
+	require.FileExists(t, oldSegment)
+
+	// Reopen with a retention policy, compaction runs right away in the background
+	options := store.DefaultOptions()
+	options.Retention = store.RetentionPolicy{RawRetention: 24 * time.Hour, CompactionInterval: time.Hour}
+	compacted, err := store.NewFileStoreWithOptions(dir, options)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(oldSegment)
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	minutes := compacted.GetRollups("https://retention.com", time.Minute, time.Time{}, time.Time{})
+	require.Len(t, minutes, 2)
+	assert.Equal(t, 3, minutes[0].Count)
+	assert.Equal(t, 0, minutes[0].Failures)
+	assert.Equal(t, 1, minutes[1].Count)
+	assert.Equal(t, 1, minutes[1].Failures)
+
+	hours := compacted.GetRollups("https://retention.com", time.Hour, time.Time{}, time.Time{})
+	require.Len(t, hours, 1)
+	assert.Equal(t, old, hours[0].Start)
+	assert.Equal(t, 4, hours[0].Count)
+	assert.Equal(t, 1, hours[0].Failures)
+	assert.Equal(t, 100.0, hours[0].LatencyMin)
+	assert.Equal(t, 400.0, hours[0].LatencyMax)
+	assert.Equal(t, 250.0, hours[0].LatencyAverage)
+	assert.Equal(t, 200.0, hours[0].LatencyP50)
+	assert.Equal(t, 400.0, hours[0].LatencyP95)
+	require.NoError(t, compacted.Close())
+
+	// Metrics survive the compaction and the next restart
+	reopened, err := store.NewFileStore(dir)
+	require.NoError(t, err)
+	defer func() { _ = reopened.Close() }()
+	metrics := reopened.GetMetrics()["https://retention.com"]
+	assert.Equal(t, 5, metrics.TotalRequests)
+	assert.Equal(t, 1, metrics.FailedRequests)
+	assert.Equal(t, 50.0, metrics.LatencyMin)
+	assert.Equal(t, 400.0, metrics.LatencyMax)
+	assert.Equal(t, 210.0, metrics.LatencyAverage)
+}
+
+func TestRetentionDeletesExpiredRollups(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	fileStore, err := store.NewFileStore(dir)
+	require.NoError(t, err)
+	old := time.Now().UTC().Add(-96 * time.Hour)
+	fileStore.SaveResult("https://expired.com", resultAt(old, 200, 100*time.Millisecond))
+	require.NoError(t, fileStore.Close())
+
+	options := store.DefaultOptions()
+	options.Retention = store.RetentionPolicy{
+		RawRetention:    24 * time.Hour,
+		MinuteRetention: 48 * time.Hour,
+		HourRetention:   72 * time.Hour,
+	}
+	compacted, err := store.NewFileStoreWithOptions(dir, options)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		rollups, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+		return len(rollups) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, compacted.Close())
+}
+
+func TestRetentionKeepsLateResultsDuringCompaction(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	// A large segment, so that the compaction takes a while
+	fileStore, err := store.NewFileStore(dir)
+	require.NoError(t, err)
+	old := time.Now().UTC().Add(-72 * time.Hour).Truncate(24 * time.Hour)
+	const early = 20000
+	for i := 0; i < early; i++ {
+		fileStore.SaveResult("https://late.com", resultAt(old.Add(time.Duration(i)*time.Second), 200, 100*time.Millisecond))
+	}
+	require.NoError(t, fileStore.Close())
+
+	// Late results of the same day keep coming in while the segment is compacted
+	options := store.DefaultOptions()
+	options.Retention = store.RetentionPolicy{RawRetention: 24 * time.Hour, CompactionInterval: time.Hour}
+	compacted, err := store.NewFileStoreWithOptions(dir, options)
+	require.NoError(t, err)
+	hourRollup := filepath.Join(dir, "rollup-1h-"+old.Format("2006-01-02")+".jsonl")
+	late := 0
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); late++ {
+		if _, err := os.Stat(hourRollup); err == nil {
+			break
+		}
+		compacted.SaveResult("https://late.com", resultAt(old.Add(time.Duration(early+late)*time.Second), 200, 100*time.Millisecond))
+	}
+	require.FileExists(t, hourRollup)
+	require.NoError(t, compacted.Close())
+
+	// Every result is either in the rollup or in the raw segment written after the compaction
+	total := 0
+	for _, bucket := range compacted.GetRollups("https://late.com", time.Hour, time.Time{}, time.Time{}) {
+		total += bucket.Count
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "results-"+old.Format("2006-01-02")+".jsonl")); err == nil {
+		total += strings.Count(string(content), "\n")
+	}
+	assert.Positive(t, late)
+	assert.Equal(t, early+late, total)
+}
+
+func TestRetentionMergesLateResultsIntoCompactedDays(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	old := time.Now().UTC().Add(-72 * time.Hour).Truncate(time.Hour)
+	options := store.DefaultOptions()
+	options.Retention = store.RetentionPolicy{RawRetention: 24 * time.Hour, CompactionInterval: time.Hour}
+	hourRollups := func(fileStore *store.FileStore) (count, failures int) {
+		for _, bucket := range fileStore.GetRollups("https://merge.com", time.Hour, time.Time{}, time.Time{}) {
+			count += bucket.Count
+			failures += bucket.Failures
+		}
+		return count, failures
+	}
+	compactedStore := func() *store.FileStore {
+		fileStore, err := store.NewFileStoreWithOptions(dir, options)
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool {
+			segments, _ := filepath.Glob(filepath.Join(dir, "*-"+old.Format("2006-01-02")+"*.jsonl"))
+			for _, segment := range segments {
+				if !strings.Contains(segment, "rollup-") {
+					return false
+				}
+			}
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+		return fileStore
+	}
+
+	fileStore, err := store.NewFileStore(dir)
+	require.NoError(t, err)
+	fileStore.SaveResult("https://merge.com", resultAt(old, 200, 100*time.Millisecond))
+	require.NoError(t, fileStore.Close())
+	fileStore = compactedStore()
+
+	// A late result of the compacted day starts a new raw segment, which counts right away
+	fileStore.SaveResult("https://merge.com", resultAt(old.Add(time.Minute), 500, 300*time.Millisecond))
+	require.NoError(t, fileStore.Close())
+	fileStore, err = store.NewFileStore(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, fileStore.GetMetrics()["https://merge.com"].TotalRequests)
+	require.NoError(t, fileStore.Close())
+
+	// The next compaction merges it into the rollups instead of dropping it
+	fileStore = compactedStore()
+	count, failures := hourRollups(fileStore)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 1, failures)
+	hours := fileStore.GetRollups("https://merge.com", time.Hour, time.Time{}, time.Time{})
+	require.Len(t, hours, 1)
+	assert.Equal(t, 100.0, hours[0].LatencyMin)
+	assert.Equal(t, 300.0, hours[0].LatencyMax)
+	assert.Equal(t, 200.0, hours[0].LatencyAverage)
+	assert.Len(t, fileStore.GetRollups("https://merge.com", time.Minute, time.Time{}, time.Time{}), 2)
+	require.NoError(t, fileStore.Close())
+
+	// A batch left over by a crash is merged only once
+	rollup, err := os.ReadFile(filepath.Join(dir, "rollup-1h-"+old.Format("2006-01-02")+".jsonl"))
+	require.NoError(t, err)
+	var header struct{ Batches []string }
+	require.NoError(t, json.Unmarshal([]byte(strings.SplitN(string(rollup), "\n", 2)[0]), &header))
+	require.Len(t, header.Batches, 2)
+
+	fileStore, err = store.NewFileStore(dir)
+	require.NoError(t, err)
+	fileStore.SaveResult("https://merge.com", resultAt(old.Add(2*time.Minute), 200, 200*time.Millisecond))
+	require.NoError(t, fileStore.Close())
+	raw := filepath.Join(dir, "results-"+old.Format("2006-01-02")+".jsonl")
+	merged := filepath.Join(dir, "compacting-"+header.Batches[1]+".jsonl")
+	pending := filepath.Join(dir, "compacting-"+old.Format("2006-01-02")+"-1.jsonl")
+	require.NoError(t, copyFile(raw, merged))
+	require.NoError(t, os.Rename(raw, pending))
+
+	fileStore, err = store.NewFileStore(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 3, fileStore.GetMetrics()["https://merge.com"].TotalRequests)
+	require.NoError(t, fileStore.Close())
+	fileStore = compactedStore()
+	count, _ = hourRollups(fileStore)
+	assert.Equal(t, 3, count)
+	assert.NoFileExists(t, merged)
+	assert.NoFileExists(t, pending)
+	require.NoError(t, fileStore.Close())
+}
+
+func copyFile(from, to string) error {
+	content, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(to, content, 0o644)
+}
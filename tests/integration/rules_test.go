@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/config"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/notifier"
+	"GoHealthChecker/internal/rules"
+	"GoHealthChecker/internal/store"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleExpressions(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	history := make([]model.HealthCheckResult, 0)
+	for i := 0; i < 10; i++ {
+		result := resultAt(now.Add(time.Duration(i-10)*time.Minute), 200, time.Duration(100*(i+1))*time.Millisecond)
+		if i >= 7 {
+			result = model.NewHealthCheckResultWithError(errors.New("timeout"), time.Second)
+			result.Timestamp = now.Add(time.Duration(i-10) * time.Minute)
+		}
+		history = append(history, result)
+	}
+	latest := history[len(history)-1]
+	latest.CertExpiry = now.Add(10 * 24 * time.Hour)
+	data := rules.Data{Now: now, Latest: latest, History: history}
+
+	cases := []struct {
+		expression string
+		matches    bool
+		described  string
+	}{
+		{"p95(latency, 5m) > 800ms", true, "p95(latency, 5m) = 1s > 800ms"},
+		{"avg(latency, 3m) < 1s", false, "avg(latency, 3m) = 1s < 1s"},
+		{"uptime(1h) < 99%", true, "uptime(1h) = 70.00% < 99.00%"},
+		{"uptime(4m) >= 50%", false, "uptime(4m) = 25.00% >= 50.00%"},
+		{"failures(5) >= 3", true, "failures(5) = 3 >= 3"},
+		{"cert_expiry < 14d", true, "cert_expiry = 240h0m0s < 336h0m0s"},
+		{"max(size, 10m) > 1KB", false, "max(size, 10m) = 40B > 1024B"},
+		{"status_code != 200", true, "status_code = 0 != 200"},
+	}
+	for _, c := range cases {
+		expression, err := rules.ParseExpression(c.expression)
+		require.NoError(t, err, c.expression)
+		value, matches, ok := expression.Evaluate(data)
+		require.True(t, ok, c.expression)
+		assert.Equal(t, c.matches, matches, c.expression)
+		assert.Equal(t, c.described, expression.Describe(value), c.expression)
+	}
+
+	for _, invalid := range []string{"p95(latency) > 1s", "median(latency, 5m) > 1s", "uptime(1h) < 99ms", "failures(0) > 1", "latency >"} {
+		_, err := rules.ParseExpression(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	// Without TLS there is no certificate to expire
+	expression, err := rules.ParseExpression("cert_expiry < 14d")
+	require.NoError(t, err)
+	_, _, ok := expression.Evaluate(rules.Data{Now: now, Latest: history[0]})
+	assert.False(t, ok)
+}
+
+func TestRuleEngineFiresAfterForAndResolves(t *testing.T) {
+	t.Parallel()
+	memoryStore := store.NewInMemoryStore()
+	require.NoError(t, memoryStore.AddURL("https://slow.com"))
+	require.NoError(t, memoryStore.AddURL("https://fast.com"))
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		memoryStore.SaveResult("https://slow.com", resultAt(start.Add(time.Duration(i)*time.Second), 200, 900*time.Millisecond))
+		memoryStore.SaveResult("https://fast.com", resultAt(start.Add(time.Duration(i)*time.Second), 200, 50*time.Millisecond))
+	}
+
+	rule, err := rules.NewRule("slow-responses", "p95(latency, 5m) > 800ms", "critical", time.Minute)
+	require.NoError(t, err)
+	rule.WithLabels(map[string]string{"team": "web"})
+	engine := rules.NewEngine(memoryStore, []*rules.Rule{rule}, time.Second, time.Second)
+
+	// The condition holds, but not for a whole minute yet
+	assert.Empty(t, engine.Evaluate(start.Add(5*time.Second)))
+	alerts := engine.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, rules.AlertPending, alerts[0].State)
+	assert.Equal(t, "https://slow.com", alerts[0].Target)
+
+	firing := engine.Evaluate(start.Add(65 * time.Second))
+	require.Len(t, firing, 1)
+	assert.Equal(t, "https://slow.com", firing[0].Target)
+	assert.Equal(t, "slow-responses", firing[0].Alert.Rule)
+	assert.Equal(t, "web", firing[0].Alert.Labels["team"])
+	assert.False(t, firing[0].Resolved())
+	assert.Equal(t, "[critical] slow-responses FIRING for https://slow.com: p95(latency, 5m) = 900ms > 800ms", firing[0].Summary())
+	assert.NotEmpty(t, firing[0].History)
+	assert.Equal(t, rules.AlertFiring, engine.Alerts()[0].State)
+	// Still firing, nothing new to notify
+	assert.Empty(t, engine.Evaluate(start.Add(70*time.Second)))
+
+	for i := 0; i < 100; i++ {
+		memoryStore.SaveResult("https://slow.com", resultAt(start.Add(80*time.Second+time.Duration(i)*time.Second), 200, 100*time.Millisecond))
+	}
+	resolved := engine.Evaluate(start.Add(180 * time.Second))
+	require.Len(t, resolved, 1)
+	assert.True(t, resolved[0].Resolved())
+	assert.Equal(t, firing[0].DedupKey(), resolved[0].DedupKey())
+	assert.NotEqual(t, notifier.DedupKey("https://slow.com"), resolved[0].DedupKey())
+	assert.Empty(t, engine.Alerts())
+}
+
+func TestRuleEngineNotifiesWebhooks(t *testing.T) {
+	t.Parallel()
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver.handler(func() int { return http.StatusOK }))
+	defer server.Close()
+
+	memoryStore := store.NewInMemoryStore()
+	require.NoError(t, memoryStore.AddURL("https://flaky.com"))
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		result := model.NewHealthCheckResultWithError(errors.New("connection refused"), time.Millisecond)
+		if i%2 == 0 {
+			result = resultAt(now, 200, time.Millisecond)
+		}
+		result.Timestamp = now.Add(time.Duration(i-5) * time.Second)
+		memoryStore.SaveResult("https://flaky.com", result)
+	}
+
+	cfg, err := config.Parse([]byte(`{"rules": [{"name": "flaky", "expr": "failures(5) >= 2", "labels": {"team": "api"}}]}`))
+	require.NoError(t, err)
+	alertRules, err := cfg.NewRules()
+	require.NoError(t, err)
+	dispatcher := notifier.NewDispatcher([]notifier.Notifier{notifier.NewWebhookNotifier(server.URL, nil)}, fastRetries())
+	engine := rules.NewEngine(memoryStore, alertRules, 0, time.Second).WithDispatcher(dispatcher)
+
+	require.Len(t, engine.Evaluate(now), 1)
+	dispatcher.Close()
+
+	payloads := receiver.received()
+	require.Len(t, payloads, 1)
+	alert := payloads[0]["alert"].(map[string]any)
+	assert.Equal(t, "flaky", alert["rule"])
+	assert.Equal(t, "warning", alert["severity"])
+	assert.Equal(t, "firing", alert["status"])
+	assert.Equal(t, "failures(5) = 2 >= 2", alert["condition"])
+	assert.Equal(t, map[string]any{"team": "api"}, alert["labels"])
+}
+
+func TestInvalidRulesInConfig(t *testing.T) {
+	t.Parallel()
+	for _, data := range []string{
+		`{"rules": [{"expr": "uptime(1h) < 99%"}]}`,
+		`{"rules": [{"name": "a", "expr": "uptime(1h) < 99%"}, {"name": "a", "expr": "latency > 1s"}]}`,
+		`{"rules": [{"name": "a", "expr": "uptime < 99%"}]}`,
+		`{"rules": [{"name": "a", "expr": "latency > 1s", "severity": "fatal"}]}`,
+	} {
+		_, err := config.Parse([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
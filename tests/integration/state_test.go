@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/config"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/state"
+	"GoHealthChecker/internal/store"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallAndRiseThresholds(t *testing.T) {
+	t.Parallel()
+	settings := model.NewAppSettings().WithFallRise(3, 2)
+	tracker := state.NewTracker(*settings)
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	url := "https://fallrise.com"
+
+	statuses := []int{200, 500, 500, 200, 500, 500, 500, 200, 500, 200, 200, 500}
+	expected := []model.State{
+		model.StateUp, model.StateUp, model.StateUp, model.StateUp, model.StateUp, model.StateUp,
+		model.StateDown, model.StateDown, model.StateDown, model.StateDown, model.StateUp, model.StateUp,
+	}
+	transitions := make([]*state.Transition, 0)
+	for i, status := range statuses {
+		result, transition := tracker.Observe(url, resultAt(start.Add(time.Duration(i)*time.Second), status, 0))
+		assert.Equal(t, expected[i], result.State, "check #%d", i)
+		if transition != nil {
+			transitions = append(transitions, transition)
+		}
+	}
+
+	require.Len(t, transitions, 3)
+	assert.Equal(t, model.StateUnknown, transitions[0].From)
+	assert.Equal(t, model.StateUp, transitions[0].To)
+	assert.Equal(t, model.StateDown, transitions[1].To)
+	assert.Equal(t, start.Add(6*time.Second), transitions[1].Timestamp)
+	assert.Equal(t, model.StateUp, transitions[2].To)
+	assert.Equal(t, model.StateUp, tracker.State(url))
+	assert.Equal(t, start.Add(10*time.Second), tracker.Since(url))
+}
+
+func TestPerTargetThresholdsFromConfig(t *testing.T) {
+	t.Parallel()
+	appConfig, err := config.Parse([]byte(`{
+		"fall": 2,
+		"targets": [
+			{"url": "https://strict.com", "fall": 1},
+			{"url": "https://lenient.com", "fall": 4, "rise": 3}
+		]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://strict.com", "https://lenient.com"}, appConfig.URLs())
+
+	settings := model.NewAppSettings()
+	appConfig.Apply(settings)
+	assert.Equal(t, model.TargetSettings{Fall: 1, Rise: 1}, settings.TargetSettings("https://strict.com"))
+	assert.Equal(t, model.TargetSettings{Fall: 4, Rise: 3}, settings.TargetSettings("https://lenient.com"))
+	assert.Equal(t, model.TargetSettings{Fall: 2, Rise: 1}, settings.TargetSettings("https://other.com"))
+
+	_, err = config.Parse([]byte(`{"targets": [{"fall": 2}]}`))
+	assert.Error(t, err)
+}
+
+func TestIncidentsFollowEffectiveState(t *testing.T) {
+	t.Parallel()
+	tracker := state.NewTracker(*model.NewAppSettings().WithFallRise(2, 1))
+	memoryStore := store.NewInMemoryStore()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	url := "https://effective.com"
+
+	// A single failure is absorbed by the fall threshold
+	for i, status := range []int{200, 500, 200, 500, 500, 200} {
+		result, _ := tracker.Observe(url, resultAt(start.Add(time.Duration(i)*time.Minute), status, 0))
+		memoryStore.SaveResult(url, result)
+	}
+
+	incidents := memoryStore.GetIncidents(url)
+	require.Len(t, incidents, 1)
+	assert.Equal(t, start.Add(4*time.Minute), incidents[0].Start)
+	assert.Equal(t, start.Add(5*time.Minute), incidents[0].End)
+	// Raw request counters are not affected
+	assert.Equal(t, 3, memoryStore.GetMetrics()[url].FailedRequests)
+}
@@ -0,0 +1,134 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/controller"
+	"GoHealthChecker/internal/model"
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/internal/store"
+	"GoHealthChecker/internal/view"
+	"GoHealthChecker/tests"
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTCPServer listens on a random local port and answers every connection with handle.
+func startTCPServer(t *testing.T, handle func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				handle(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// closedPort returns the address of a local port nobody listens on.
+func closedPort(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return address
+}
+
+func TestTCPServiceConnectAndBanner(t *testing.T) {
+	t.Parallel()
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	tcpService := service.NewTCPService(settings)
+
+	redis := startTCPServer(t, func(conn net.Conn) {
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err == nil && strings.TrimSpace(line) == "PING" {
+			_, _ = conn.Write([]byte("+PONG\r\n"))
+		} else {
+			_, _ = conn.Write([]byte("-ERR unknown command\r\n"))
+		}
+	})
+	silent := startTCPServer(t, func(conn net.Conn) {
+		time.Sleep(3 * time.Second)
+	})
+
+	result, err := tcpService.CheckUrl("tcp://" + redis)
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Equal(t, 0, result.StatusCode)
+	assert.Positive(t, result.Latency)
+
+	result, err = tcpService.CheckUrl("tcp://" + redis + "?send=PING%0D%0A&expect=%2BPONG")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Equal(t, uint64(7), result.Size)
+
+	result, err = tcpService.CheckUrl("tcp://" + redis + "?send=HELLO%0D%0A&expect=%2BPONG")
+	require.Error(t, err)
+	assert.False(t, result.IsOk)
+	assert.Contains(t, result.ErrorMessage(), `expected "+PONG", got "-ERR unknown command\r\n"`)
+
+	start := time.Now()
+	_, err = tcpService.CheckUrl("tcp://" + silent + "?expect=SSH-2.0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout")
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	result, err = tcpService.CheckUrl("tcp://" + closedPort(t))
+	require.Error(t, err)
+	assert.Contains(t, result.ErrorMessage(), "connection refused")
+}
+
+func TestTCPTargetsThroughController(t *testing.T) {
+	t.Parallel()
+	output, _, cancel, settings := tests.CreateConfiguration(1, 1)
+	settings.WithPollingInterval(100 * time.Millisecond)
+	settings.Timeout = time.Second
+
+	postgres := startTCPServer(t, func(conn net.Conn) {})
+	down := closedPort(t)
+	httpmockTransport := httpmock.NewMockTransport()
+	httpmockTransport.RegisterResponder("GET", "https://web.com", httpmock.NewStringResponder(http.StatusOK, "ok"))
+	checker := service.NewRegistry(service.NewHTTPServiceWithTransport(httpmockTransport, settings)).
+		WithScheme("tcp", service.NewTCPService(settings))
+
+	resultStore := store.NewInMemoryStore()
+	appController := controller.NewController(resultStore, view.NewCLIView(settings), checker, settings)
+	done := make(chan struct{})
+	go func() {
+		_ = appController.Start([]string{"tcp://" + postgres, "tcp://" + down, "https://web.com"})
+		close(done)
+	}()
+	time.Sleep(350 * time.Millisecond)
+	cancel()
+	<-done
+
+	metrics := resultStore.GetMetrics()
+	assert.Positive(t, metrics["tcp://"+postgres].TotalRequests)
+	assert.Equal(t, 0, metrics["tcp://"+postgres].FailedRequests)
+	assert.Equal(t, metrics["tcp://"+down].TotalRequests, metrics["tcp://"+down].FailedRequests)
+	assert.Equal(t, model.StateDown, resultStore.GetLatestResults()["tcp://"+down].State)
+	assert.Positive(t, metrics["https://web.com"].TotalRequests)
+	rows := tests.ParseLinesForURL(output.String(), "tcp://"+postgres)
+	require.NotEmpty(t, rows)
+	assert.Equal(t, "UP", rows[0][1])
+
+	for _, invalid := range []string{"tcp://db.internal", "gopher://db.internal:70"} {
+		assert.Error(t, defaultRegistry.ValidateURL(invalid), invalid)
+	}
+	assert.NoError(t, defaultRegistry.ValidateURL("tcp://db.internal:5432?send=PING"))
+}
@@ -0,0 +1,74 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/tests"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startUDPServer answers every datagram with reply, nothing if the reply is empty.
+func startUDPServer(t *testing.T, reply func(request string) string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		buffer := make([]byte, 1024)
+		for {
+			n, from, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := reply(string(buffer[:n])); response != "" {
+				_, _ = conn.WriteTo([]byte(response), from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestUDPRequestResponse(t *testing.T) {
+	t.Parallel()
+	echo := startUDPServer(t, func(request string) string {
+		if request == "PING" {
+			return "PONG 42"
+		}
+		return ""
+	})
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = 300 * time.Millisecond
+	udpService := service.NewUDPService(settings)
+
+	for _, url := range []string{
+		"udp://" + echo + "?send=PING&expect=PONG",
+		"udp://" + echo + "?send=PING&match=" + "%5EPONG+%5B0-9%5D%2B%24",
+		"udp://" + echo + "?send=%3C13%3Ehealthcheck&response=optional",
+	} {
+		result, err := udpService.CheckUrl(url)
+		require.NoError(t, err, url)
+		assert.True(t, result.IsOk, url)
+	}
+	result, _ := udpService.CheckUrl("udp://" + echo + "?send=PING")
+	assert.Equal(t, uint64(7), result.Size)
+
+	failing := map[string]string{
+		"udp://" + echo + "?send=PING&expect=PANG":         `expected "PANG", got "PONG 42"`,
+		"udp://" + echo + "?send=PING&match=%5EPANG":       `expected to match "^PANG", got "PONG 42"`,
+		"udp://" + echo + "?send=HELLO":                    "no response within 300ms",
+		"udp://" + closedUDPPort(t) + "?send=PING":         "port unreachable",
+		"udp://" + closedUDPPort(t) + "?response=optional": "port unreachable",
+	}
+	for url, message := range failing {
+		result, err := udpService.CheckUrl(url)
+		require.Error(t, err, url)
+		assert.False(t, result.IsOk, url)
+		assert.Contains(t, result.ErrorMessage(), message, url)
+	}
+
+	assert.NoError(t, defaultRegistry.ValidateURL("udp://syslog.internal:514?send=ping&response=optional"))
+	assert.Error(t, defaultRegistry.ValidateURL("udp://syslog.internal"))
+}
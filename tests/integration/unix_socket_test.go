@@ -0,0 +1,67 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/tests"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startUnixServer serves handler over HTTP on a Unix domain socket and returns the path of the socket.
+func startUnixServer(t *testing.T, handler http.Handler) string {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: time.Second}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return socket
+}
+
+func TestHTTPOverUnixSocket(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "localhost" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok","verbose":"` + r.URL.Query().Get("verbose") + `"}`))
+	})
+	mux.HandleFunc("GET /ready", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	socket := startUnixServer(t, mux)
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	checker := service.NewRegistry(service.NewHTTPService(settings))
+
+	result, err := checker.CheckUrl("unix://" + socket + ":/healthz?verbose=1")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Equal(t, uint64(len(`{"status":"ok","verbose":"1"}`)), result.Size)
+	assert.Positive(t, result.Latency)
+
+	// The root path by default
+	result, err = checker.CheckUrl("unix://" + socket)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+
+	result, err = checker.CheckUrl("unix://" + socket + ":/ready")
+	require.NoError(t, err)
+	assert.False(t, result.IsOk)
+	assert.Equal(t, "HTTP 503", result.ErrorMessage())
+
+	_, err = checker.CheckUrl("unix://" + filepath.Join(t.TempDir(), "missing.sock") + ":/healthz")
+	assert.ErrorContains(t, err, "no such file or directory")
+
+	assert.NoError(t, defaultRegistry.ValidateURL("unix:///var/run/docker.sock:/_ping"))
+	assert.Error(t, defaultRegistry.ValidateURL("unix://app.sock:/healthz"))
+}
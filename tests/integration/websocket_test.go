@@ -0,0 +1,142 @@
+package integration
+
+import (
+	"GoHealthChecker/internal/service"
+	"GoHealthChecker/tests"
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webSocketEcho upgrades the connection and answers text messages by reply,
+// after a ping to check that the client answers it.
+func webSocketEcho(reply func(message string) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed" || r.URL.Query().Get("send") != "" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		accept := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		conn, buffer, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n")
+		_ = buffer.Flush()
+
+		for {
+			opcode, payload, err := readClientFrame(buffer.Reader)
+			if err != nil || opcode == 0x8 {
+				return
+			}
+			if opcode != 0x1 {
+				continue
+			}
+			answer := reply(string(payload))
+			if answer == "" {
+				// Close with going away
+				_, _ = conn.Write([]byte{0x88, 2, 0x03, 0xE9})
+				return
+			}
+			_, _ = conn.Write([]byte{0x89, 0})
+			_, _ = conn.Write(append([]byte{0x81, byte(len(answer))}, answer...))
+		}
+	}
+}
+
+// readClientFrame reads a short masked frame sent by the client.
+func readClientFrame(reader *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 6)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, header[1]&0x7F)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= header[2+i%4]
+	}
+	return header[0] & 0x0F, payload, nil
+}
+
+func TestWebSocketHandshakeAndEcho(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(webSocketEcho(func(message string) string {
+		switch message {
+		case "ping":
+			return "pong"
+		case "bye":
+			return ""
+		default:
+			return message
+		}
+	}))
+	defer server.Close()
+	base := "ws://" + strings.TrimPrefix(server.URL, "http://")
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	webSocketService := service.NewWebSocketService(settings)
+
+	result, err := webSocketService.CheckUrl(base + "/feed")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Equal(t, http.StatusSwitchingProtocols, result.StatusCode)
+	assert.Positive(t, result.Latency)
+	assert.Zero(t, result.RoundTrip)
+
+	result, err = webSocketService.CheckUrl(base + "/feed?send=hello")
+	require.NoError(t, err)
+	assert.Positive(t, result.RoundTrip)
+	assert.Equal(t, uint64(5), result.Size)
+
+	result, err = webSocketService.CheckUrl(base + "/feed?send=ping&expect=pong")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+
+	failing := map[string]string{
+		base + "/feed?send=ping&expect=PONG": `expected "PONG"`,
+		base + "/feed?send=bye":              "connection closed by the server with code 1001",
+		base + "/missing":                    "handshake failed: HTTP 404",
+		"ws://" + closedPort(t) + "/feed":    "connection refused",
+	}
+	for url, message := range failing {
+		result, err := webSocketService.CheckUrl(url)
+		require.Error(t, err, url)
+		assert.False(t, result.IsOk, url)
+		assert.Contains(t, result.ErrorMessage(), message, url)
+	}
+	result, _ = webSocketService.CheckUrl(base + "/missing")
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+
+	assert.NoError(t, defaultRegistry.ValidateURL("wss://rt.example.com/feed?send=ping"))
+}
+
+func TestWebSocketOverTLS(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(webSocketEcho(func(message string) string { return message }))
+	defer server.Close()
+	base := "wss://" + strings.TrimPrefix(server.URL, "https://")
+	_, _, _, settings := tests.CreateConfiguration(1, 1)
+	settings.Timeout = time.Second
+	webSocketService := service.NewWebSocketService(settings)
+
+	result, err := webSocketService.CheckUrl(base + "/feed?send=hello&insecure_skip_verify=true")
+	require.NoError(t, err)
+	assert.True(t, result.IsOk)
+	assert.Equal(t, server.Certificate().NotAfter, result.CertExpiry)
+
+	_, err = webSocketService.CheckUrl(base + "/feed")
+	assert.ErrorContains(t, err, "certificate")
+}